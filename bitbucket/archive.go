@@ -0,0 +1,14 @@
+package bitbucket
+
+import (
+	"fmt"
+	"io"
+)
+
+// DownloadArchive writes gzipped tarball of the repo at given commit into w.
+// Files are placed in the single top level directory of the archive
+func (c Client) DownloadArchive(commitHash string, w io.Writer) error {
+	url := fmt.Sprintf("%s%s/get/%s.tar.gz", repoBaseURL, c.repoPath, commitHash)
+
+	return c.download(url, w)
+}
@@ -0,0 +1,153 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oauthTokenURL string = "https://bitbucket.org/site/oauth2/access_token"
+
+	// gitTokenUsername is a username expected by BitBucket for git over HTTPS authenticated by token
+	gitTokenUsername string = "x-token-auth"
+)
+
+// Auth holds credentials for BitBucket REST API and git over HTTPS.
+// First configured method wins: access token, OAuth consumer, username with app password
+type Auth struct {
+	Username    string
+	Password    string
+	AccessToken string
+	OAuthKey    string
+	OAuthSecret string
+
+	mutex       sync.Mutex
+	oauthToken  string
+	oauthExpiry time.Time
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (a *Auth) isOAuth() bool {
+	return len(a.AccessToken) == 0 && len(a.OAuthKey) > 0
+}
+
+// authorize sets Authorization header of req according to configured method
+func (a *Auth) authorize(req *http.Request, httpClient *http.Client) error {
+	if len(a.AccessToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+a.AccessToken)
+		return nil
+	}
+
+	if a.isOAuth() {
+		token, err := a.token(httpClient)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// invalidate drops cached OAuth token, so the next request fetches a new one
+func (a *Auth) invalidate() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.oauthToken = ""
+}
+
+// GitCredentials returns username and password for git over HTTPS
+func (a *Auth) GitCredentials(httpClient *http.Client) (string, string, error) {
+	if len(a.AccessToken) > 0 {
+		return gitTokenUsername, a.AccessToken, nil
+	}
+
+	if a.isOAuth() {
+		token, err := a.token(httpClient)
+		if err != nil {
+			return "", "", err
+		}
+
+		return gitTokenUsername, token, nil
+	}
+
+	return a.Username, a.Password, nil
+}
+
+// token returns cached OAuth access token or obtains new one by client credentials grant
+func (a *Auth) token(httpClient *http.Client) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(a.oauthToken) > 0 && time.Now().Before(a.oauthExpiry) {
+		return a.oauthToken, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	req, err := http.NewRequest("POST", oauthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("bitbucket/auth: http req creation: %w", err)
+	}
+
+	req.SetBasicAuth(a.OAuthKey, a.OAuthSecret)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("bitbucket/auth: http req to %s: %w", oauthTokenURL, err)
+	}
+	defer res.Body.Close()
+
+	buf := new(bytes.Buffer)
+
+	_, err = io.Copy(buf, res.Body)
+	if err != nil {
+		return "", fmt.Errorf("bitbucket/auth: read body: %w", err)
+	}
+
+	if res.StatusCode != 200 {
+		return "", fmt.Errorf("bitbucket/auth: %s: %s, %s", res.Status, oauthTokenURL, buf.Bytes())
+	}
+
+	var tokenRes oauthTokenResponse
+
+	err = json.NewDecoder(buf).Decode(&tokenRes)
+	if err != nil {
+		return "", fmt.Errorf("bitbucket/auth: decode token: %w", err)
+	}
+
+	a.oauthToken = tokenRes.AccessToken
+	a.oauthExpiry = time.Now().Add(tokenLifetime(tokenRes.ExpiresIn))
+
+	return a.oauthToken, nil
+}
+
+// tokenLifetime shortened by a minute, or half of it for short lived tokens, to refresh before actual expiration
+// and not race with long running requests
+func tokenLifetime(expiresIn int) time.Duration {
+	lifetime := time.Duration(expiresIn) * time.Second
+
+	margin := time.Minute
+	if lifetime/2 < margin {
+		margin = lifetime / 2
+	}
+
+	return lifetime - margin
+}
@@ -1,8 +1,11 @@
 package bitbucket
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 )
@@ -26,22 +29,23 @@ type Client struct {
 	httpClient *http.Client
 }
 
-type Auth struct {
-	Username string
-	Password string
-}
-
 type PagedResponse struct {
 	Size   int             `json:"size"`
 	Next   string          `json:"next"`
 	Values json.RawMessage `json:"values"`
 }
 
-func NewClient(workspace, slug string, auth *Auth) *Client {
+// NewClient for repository identified by workspace and slug.
+// Default http.Client is used when httpClient is nil
+func NewClient(workspace, slug string, auth *Auth, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
 	return &Client{
 		auth:       auth,
 		repoPath:   fmt.Sprintf("/%s/%s", workspace, slug),
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 	}
 }
 
@@ -62,3 +66,161 @@ func (c Client) RepoURL() string {
 func (c Client) PullrequestURL(id string) string {
 	return fmt.Sprintf("%s%s/pull-requests/%s", repoBaseURL, c.repoPath, id)
 }
+
+// do performs authorized request with optional JSON body and returns response body.
+// Non 2xx status is reported as an error. Request is retried once with a fresh OAuth token on 401
+func (c Client) do(method, url string, body interface{}) (*bytes.Buffer, error) {
+	buf, _, err := c.doResponse(method, url, body)
+
+	return buf, err
+}
+
+// doResponse as do, returning also the response for its status and headers. Body of response is already read
+func (c Client) doResponse(method, url string, body interface{}) (*bytes.Buffer, *http.Response, error) {
+	var payload []byte
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bitbucket/client: encode body: %w", err)
+		}
+
+		payload = data
+	}
+
+	buf, res, err := c.send(method, url, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized && c.auth.isOAuth() {
+		c.auth.invalidate()
+
+		buf, res, err = c.send(method, url, payload)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, nil, &Error{StatusCode: res.StatusCode, Status: res.Status, URL: url, Body: buf.String()}
+	}
+
+	return buf, res, nil
+}
+
+// send performs single request and reads whole response body
+func (c Client) send(method, url string, payload []byte) (*bytes.Buffer, *http.Response, error) {
+	var body io.Reader
+
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bitbucket/client: http req creation: %w", err)
+	}
+
+	err = c.auth.authorize(req, c.httpClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bitbucket/client: authorize: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bitbucket/client: http req to %s: %w", url, err)
+	}
+	defer res.Body.Close()
+
+	buf := new(bytes.Buffer)
+
+	_, err = io.Copy(buf, res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bitbucket/client: read body: %w", err)
+	}
+
+	return buf, res, nil
+}
+
+// download streams body of authorized GET request into w. Request is retried once with a fresh OAuth token on 401
+func (c Client) download(url string, w io.Writer) error {
+	res, err := c.get(url)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized && c.auth.isOAuth() {
+		res.Body.Close()
+		c.auth.invalidate()
+
+		res, err = c.get(url)
+		if err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		buf := new(bytes.Buffer)
+		io.Copy(buf, res.Body)
+
+		return &Error{StatusCode: res.StatusCode, Status: res.Status, URL: url, Body: buf.String()}
+	}
+
+	_, err = io.Copy(w, res.Body)
+	if err != nil {
+		return fmt.Errorf("bitbucket/client: read body: %w", err)
+	}
+
+	return nil
+}
+
+func (c Client) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: http req creation: %w", err)
+	}
+
+	err = c.auth.authorize(req, c.httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: authorize: %w", err)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: http req to %s: %w", url, err)
+	}
+
+	return res, nil
+}
+
+// Error returned by BitBucket API with non successful HTTP status
+type Error struct {
+	StatusCode int
+	Status     string
+	URL        string
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bitbucket/client: %s: %s, %s", e.Status, e.URL, e.Body)
+}
+
+// IsNotFound reports API error of missing entity, ie. deleted PR
+func IsNotFound(err error) bool {
+	var apiErr *Error
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// GitCredentials returns username and password for git over HTTPS
+func (c Client) GitCredentials() (string, string, error) {
+	return c.auth.GitCredentials(c.httpClient)
+}
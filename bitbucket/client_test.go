@@ -0,0 +1,85 @@
+package bitbucket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDoAuthorization(t *testing.T) {
+	var header string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		auth     *Auth
+		expected string
+	}{
+		{&Auth{Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+		{&Auth{Username: "user", Password: "pass", AccessToken: "token"}, "Bearer token"},
+	}
+
+	for _, c := range cases {
+		cli := NewClient("workspace", "slug", c.auth, srv.Client())
+
+		_, err := cli.do("GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header != c.expected {
+			t.Errorf("expected authorization %s, got %s", c.expected, header)
+		}
+	}
+}
+
+func TestDoErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	cli := NewClient("workspace", "slug", &Auth{AccessToken: "token"}, srv.Client())
+
+	_, err := cli.do("GET", srv.URL, nil)
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected API error with status 403, got %v", err)
+	}
+}
+
+func TestGitCredentials(t *testing.T) {
+	user, pass, err := (&Auth{AccessToken: "token"}).GitCredentials(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user != gitTokenUsername || pass != "token" {
+		t.Errorf("unexpected credentials %s:%s", user, pass)
+	}
+}
+
+func TestTokenLifetime(t *testing.T) {
+	cases := []struct {
+		expiresIn int
+		expected  time.Duration
+	}{
+		{7200, 7200*time.Second - time.Minute},
+		{120, time.Minute},
+		{60, 30 * time.Second},
+		{0, 0},
+	}
+
+	for _, c := range cases {
+		if lifetime := tokenLifetime(c.expiresIn); lifetime != c.expected {
+			t.Errorf("expires in %d: expected lifetime %s, got %s", c.expiresIn, c.expected, lifetime)
+		}
+	}
+}
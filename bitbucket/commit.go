@@ -1,11 +1,8 @@
 package bitbucket
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 )
 
 type CommitReponse struct {
@@ -34,29 +31,9 @@ type CommitBuildStatusRequest struct {
 func (c Client) GetCommits(branch string) ([]CommitReponse, error) {
 	url := c.APIURL(commitsEndpoint, branch)
 
-	req, err := http.NewRequest("GET", url, nil)
+	buf, err := c.do("GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: http req creation: %w", err)
-	}
-
-	req.SetBasicAuth(c.auth.Username, c.auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: http req to %s: %w", url, err)
-	}
-	defer res.Body.Close()
-
-	buf := new(bytes.Buffer)
-
-	_, err = io.Copy(buf, res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: read body: %w", err)
-	}
-
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("bitbucket/client: %s: %s, %s", res.Status, url, buf.Bytes())
+		return nil, err
 	}
 
 	var pagedResponse PagedResponse
@@ -76,39 +53,54 @@ func (c Client) GetCommits(branch string) ([]CommitReponse, error) {
 	return commitsPage, nil
 }
 
-func (c Client) SetCommitBuildStatus(commitHash string, statReq *CommitBuildStatusRequest) error {
-	url := c.APIURL(commitEndpoint, commitHash, "statuses", "build")
-
-	data := new(bytes.Buffer)
-	err := json.NewEncoder(data).Encode(statReq)
+// GetCommit fetches single commit, ie. to resolve full hash of abbreviated one
+func (c Client) GetCommit(hash string) (*CommitReponse, error) {
+	buf, err := c.do("GET", c.APIURL(commitEndpoint, hash), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, data)
+	var commit CommitReponse
+
+	err = json.NewDecoder(buf).Decode(&commit)
 	if err != nil {
-		return fmt.Errorf("bitbucket/client: http req creation: %w", err)
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
 	}
 
-	req.SetBasicAuth(c.auth.Username, c.auth.Password)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	return &commit, nil
+}
 
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("bitbucket/client: http req to %s: %w", url, err)
-	}
+func (c Client) SetCommitBuildStatus(commitHash string, statReq *CommitBuildStatusRequest) error {
+	url := c.APIURL(commitEndpoint, commitHash, "statuses", "build")
 
-	buf := new(bytes.Buffer)
+	_, err := c.do("POST", url, statReq)
 
-	_, err = io.Copy(buf, res.Body)
-	if err != nil {
-		return fmt.Errorf("bitbucket/client: read body: %w", err)
-	}
+	return err
+}
+
+// GetCommitBuildStatuses fetches all build statuses set on the commit. Results are autopaged
+func (c Client) GetCommitBuildStatuses(commitHash string) ([]CommitBuildStatusRequest, error) {
+	values := make([]CommitBuildStatusRequest, 0)
+
+	url := c.APIURL(commitEndpoint, commitHash, "statuses")
+	url = fmt.Sprintf("%s?pagelen=%d", url, 100)
+
+	for len(url) > 0 {
+		resp, err := c.getPage(url)
+		if err != nil {
+			return nil, err
+		}
+
+		var valuesPage []CommitBuildStatusRequest
+
+		err = json.Unmarshal([]byte(resp.Values), &valuesPage)
+		if err != nil {
+			return nil, fmt.Errorf("bitbucket/client: unmarshal paged values: %w", err)
+		}
 
-	if res.StatusCode != 201 && res.StatusCode != 200 {
-		return fmt.Errorf("bitbucket/client: %s: %s, %s", res.Status, url, buf.Bytes())
+		values = append(values, valuesPage...)
+		url = resp.Next
 	}
 
-	return nil
+	return values, nil
 }
@@ -1,11 +1,8 @@
 package bitbucket
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 )
 
 // PullRequestState indicatees state of PullRequest
@@ -29,6 +26,7 @@ const (
 type PullRequestEntity struct {
 	ID              int              `json:"id"`
 	Title           string           `json:"title"`
+	Description     string           `json:"description"`
 	State           PullRequestState `json:"state"`
 	CloseAfterMerge bool             `json:"close_source_branch"`
 	Author          GitAuthor        `json:"author"`
@@ -36,10 +34,37 @@ type PullRequestEntity struct {
 	Dest            GitReference     `json:"destination"`
 	UpdatedOn       string           `json:"updated_on"`
 	CreatedOn       string           `json:"created_on"`
+	Reviewers       []GitAuthor      `json:"reviewers"`
+	Participants    []Participant    `json:"participants"`
+	CommentCount    int              `json:"comment_count"`
+	MergeCommit     *GitCommit       `json:"merge_commit,omitempty"`
+}
+
+// Approvals counts participants who approved the PR
+func (pr PullRequestEntity) Approvals() int {
+	count := 0
+
+	for _, p := range pr.Participants {
+		if p.Approved {
+			count++
+		}
+	}
+
+	return count
 }
 
 type GitAuthor struct {
-	Name string `json:"display_name"`
+	Name      string `json:"display_name"`
+	UUID      string `json:"uuid,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+}
+
+// Participant of PR discussion, either reviewer or just commenter
+type Participant struct {
+	User     GitAuthor `json:"user"`
+	Role     string    `json:"role"`
+	Approved bool      `json:"approved"`
+	State    string    `json:"state"`
 }
 
 type GitReference struct {
@@ -62,6 +87,25 @@ type GitBranch struct {
 	Name string `json:"name"`
 }
 
+// GetPullRequest fetches single PR identified by id
+func (c Client) GetPullRequest(id string) (*PullRequestEntity, error) {
+	url := c.APIURL(pullRequestsEndpoint, id)
+
+	buf, err := c.do("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequestEntity
+
+	err = json.NewDecoder(buf).Decode(&pr)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &pr, nil
+}
+
 // GetPullRequestsPaged fetches list of PR for given repository.
 // Results are autopaged
 func (c Client) GetPullRequestsPaged() ([]PullRequestEntity, error) {
@@ -71,7 +115,7 @@ func (c Client) GetPullRequestsPaged() ([]PullRequestEntity, error) {
 	url = fmt.Sprintf("%s?pagelen=%d", url, 50)
 
 	for ok := true; ok; ok = len(url) > 0 {
-		resp, err := c.getPullRequestsSinglePage(url)
+		resp, err := c.getPage(url)
 		if err != nil {
 			return nil, err
 		}
@@ -90,38 +134,91 @@ func (c Client) GetPullRequestsPaged() ([]PullRequestEntity, error) {
 	return values, nil
 }
 
-func (c Client) getPullRequestsSinglePage(url string) (*PagedResponse, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// getPage fetches single page of paged response
+func (c Client) getPage(url string) (*PagedResponse, error) {
+	buf, err := c.do("GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: http req creation: %w", err)
+		return nil, err
 	}
 
-	req.SetBasicAuth(c.auth.Username, c.auth.Password)
-	req.Header.Set("Accept", "application/json")
+	var pullRequestResponse PagedResponse
 
-	res, err := c.httpClient.Do(req)
+	err = json.NewDecoder(buf).Decode(&pullRequestResponse)
 	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: http req to %s: %w", url, err)
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
 	}
-	defer res.Body.Close()
 
-	buf := new(bytes.Buffer)
+	return &pullRequestResponse, nil
+}
 
-	_, err = io.Copy(buf, res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: read body: %w", err)
-	}
+// GetPullRequestCommits fetches all commits of PR, newest first. Results are autopaged
+func (c Client) GetPullRequestCommits(id string) ([]CommitReponse, error) {
+	values := make([]CommitReponse, 0)
+
+	url := c.APIURL(pullRequestsEndpoint, id, "commits")
+	url = fmt.Sprintf("%s?pagelen=%d", url, 100)
+
+	for len(url) > 0 {
+		resp, err := c.getPage(url)
+		if err != nil {
+			return nil, err
+		}
+
+		var valuesPage []CommitReponse
+
+		err = json.Unmarshal([]byte(resp.Values), &valuesPage)
+		if err != nil {
+			return nil, fmt.Errorf("bitbucket/client: unmarshal paged values: %w", err)
+		}
 
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("bitbucket/client: %s: %s, %s", res.Status, url, buf.Bytes())
+		values = append(values, valuesPage...)
+		url = resp.Next
 	}
 
-	var pullRequestResponse PagedResponse
+	return values, nil
+}
 
-	err = json.NewDecoder(buf).Decode(&pullRequestResponse)
-	if err != nil {
-		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+// PullRequestUpdate is activity entry recorded when PR is created or its source branch is pushed
+type PullRequestUpdate struct {
+	Source GitReference `json:"source"`
+	Dest   GitReference `json:"destination"`
+	State  string       `json:"state"`
+	Date   string       `json:"date"`
+}
+
+type pullRequestActivity struct {
+	Update *PullRequestUpdate `json:"update,omitempty"`
+}
+
+// GetPullRequestUpdates fetches update entries of PR activity, newest first. Comments and approvals are skipped.
+// Results are autopaged
+func (c Client) GetPullRequestUpdates(id string) ([]PullRequestUpdate, error) {
+	values := make([]PullRequestUpdate, 0)
+
+	url := c.APIURL(pullRequestsEndpoint, id, "activity")
+	url = fmt.Sprintf("%s?pagelen=%d", url, 50)
+
+	for len(url) > 0 {
+		resp, err := c.getPage(url)
+		if err != nil {
+			return nil, err
+		}
+
+		var valuesPage []pullRequestActivity
+
+		err = json.Unmarshal([]byte(resp.Values), &valuesPage)
+		if err != nil {
+			return nil, fmt.Errorf("bitbucket/client: unmarshal paged values: %w", err)
+		}
+
+		for _, a := range valuesPage {
+			if a.Update != nil {
+				values = append(values, *a.Update)
+			}
+		}
+
+		url = resp.Next
 	}
 
-	return &pullRequestResponse, nil
+	return values, nil
 }
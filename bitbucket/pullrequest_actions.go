@@ -0,0 +1,288 @@
+package bitbucket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommentContent of PR comment in Markdown
+type CommentContent struct {
+	Raw string `json:"raw"`
+}
+
+// CommentEntity as posted on PR
+type CommentEntity struct {
+	ID      int            `json:"id,omitempty"`
+	Content CommentContent `json:"content"`
+	Deleted bool           `json:"deleted,omitempty"`
+}
+
+// MergeStrategy enumerates ways of merging PR
+type MergeStrategy string
+
+const (
+	// MergeCommitMergeStrategy creates merge commit
+	MergeCommitMergeStrategy MergeStrategy = "merge_commit"
+
+	// SquashMergeStrategy squashes PR commits into single one
+	SquashMergeStrategy MergeStrategy = "squash"
+
+	// FastForwardMergeStrategy moves destination branch to PR head, if possible
+	FastForwardMergeStrategy MergeStrategy = "fast_forward"
+)
+
+// MergeRequest body of PR merge
+type MergeRequest struct {
+	Type              string        `json:"type"`
+	Message           string        `json:"message,omitempty"`
+	CloseSourceBranch bool          `json:"close_source_branch"`
+	MergeStrategy     MergeStrategy `json:"merge_strategy,omitempty"`
+}
+
+// UserReference identifies user by either UUID or Atlassian account ID
+type UserReference struct {
+	UUID      string `json:"uuid,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+}
+
+// NewUserReference from UUID, recognized by curly braces, or account ID
+func NewUserReference(id string) UserReference {
+	if strings.HasPrefix(id, "{") && strings.HasSuffix(id, "}") {
+		return UserReference{UUID: id}
+	}
+
+	return UserReference{AccountID: id}
+}
+
+// BranchReference points branch as source or destination of PR being created
+type BranchReference struct {
+	Branch GitBranch `json:"branch"`
+}
+
+// CreatePullRequestRequest body of PR creation
+type CreatePullRequestRequest struct {
+	Title             string          `json:"title"`
+	Description       string          `json:"description,omitempty"`
+	Source            BranchReference `json:"source"`
+	Dest              BranchReference `json:"destination"`
+	Reviewers         []UserReference `json:"reviewers,omitempty"`
+	CloseSourceBranch bool            `json:"close_source_branch"`
+}
+
+// CreatePullRequest in the repository
+func (c Client) CreatePullRequest(createReq *CreatePullRequestRequest) (*PullRequestEntity, error) {
+	buf, err := c.do("POST", c.APIURL(pullRequestsEndpoint), createReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequestEntity
+
+	err = json.NewDecoder(buf).Decode(&pr)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &pr, nil
+}
+
+// AddPullRequestComment posts Markdown comment to PR
+func (c Client) AddPullRequestComment(id string, markdown string) (*CommentEntity, error) {
+	url := c.APIURL(pullRequestsEndpoint, id, "comments")
+
+	comment := CommentEntity{
+		Content: CommentContent{Raw: markdown},
+	}
+
+	buf, err := c.do("POST", url, &comment)
+	if err != nil {
+		return nil, err
+	}
+
+	var created CommentEntity
+
+	err = json.NewDecoder(buf).Decode(&created)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &created, nil
+}
+
+// GetPullRequestComments fetches all comments of PR. Results are autopaged
+func (c Client) GetPullRequestComments(id string) ([]CommentEntity, error) {
+	values := make([]CommentEntity, 0)
+
+	url := c.APIURL(pullRequestsEndpoint, id, "comments")
+	url = fmt.Sprintf("%s?pagelen=%d", url, 100)
+
+	for len(url) > 0 {
+		resp, err := c.getPage(url)
+		if err != nil {
+			return nil, err
+		}
+
+		var valuesPage []CommentEntity
+
+		err = json.Unmarshal([]byte(resp.Values), &valuesPage)
+		if err != nil {
+			return nil, fmt.Errorf("bitbucket/client: unmarshal paged values: %w", err)
+		}
+
+		values = append(values, valuesPage...)
+		url = resp.Next
+	}
+
+	return values, nil
+}
+
+// UpdatePullRequestComment replaces content of existing comment
+func (c Client) UpdatePullRequestComment(id string, commentID int, markdown string) (*CommentEntity, error) {
+	url := c.APIURL(pullRequestsEndpoint, id, "comments", strconv.Itoa(commentID))
+
+	comment := CommentEntity{
+		Content: CommentContent{Raw: markdown},
+	}
+
+	buf, err := c.do("PUT", url, &comment)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated CommentEntity
+
+	err = json.NewDecoder(buf).Decode(&updated)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &updated, nil
+}
+
+// ApprovePullRequest on behalf of authenticated user
+func (c Client) ApprovePullRequest(id string) error {
+	_, err := c.do("POST", c.APIURL(pullRequestsEndpoint, id, "approve"), nil)
+
+	return err
+}
+
+// UnapprovePullRequest withdraws approval of authenticated user
+func (c Client) UnapprovePullRequest(id string) error {
+	_, err := c.do("DELETE", c.APIURL(pullRequestsEndpoint, id, "approve"), nil)
+
+	return err
+}
+
+// RequestChangesPullRequest on behalf of authenticated user
+func (c Client) RequestChangesPullRequest(id string) error {
+	_, err := c.do("POST", c.APIURL(pullRequestsEndpoint, id, "request-changes"), nil)
+
+	return err
+}
+
+// DeclinePullRequest closes PR without merging
+func (c Client) DeclinePullRequest(id string) (*PullRequestEntity, error) {
+	return c.pullRequestTransition(id, "decline", nil)
+}
+
+// MergePullRequest into its destination branch. BitBucket finishes long running merge in background,
+// which is polled until done
+func (c Client) MergePullRequest(id string, mergeReq *MergeRequest) (*PullRequestEntity, error) {
+	mergeReq.Type = "pullrequest"
+
+	url := c.APIURL(pullRequestsEndpoint, id, "merge")
+
+	buf, res, err := c.doResponse("POST", url, mergeReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusAccepted {
+		return c.waitForMerge(res.Header.Get("Location"))
+	}
+
+	return decodePullRequest(buf)
+}
+
+// mergeTaskStatus of merge running in background
+type mergeTaskStatus struct {
+	Status string             `json:"task_status"`
+	Result *PullRequestEntity `json:"merge_result,omitempty"`
+}
+
+var (
+	// mergePollInterval between requests for status of merge running in background
+	mergePollInterval = 2 * time.Second
+
+	// mergePollTimeout after which merge is reported as not finished
+	mergePollTimeout = 5 * time.Minute
+)
+
+// waitForMerge polls task status of merge until it succeeds. Failed merge is reported by API with error status
+func (c Client) waitForMerge(taskURL string) (*PullRequestEntity, error) {
+	if len(taskURL) == 0 {
+		return nil, errors.New("bitbucket/client: merge accepted without task status link")
+	}
+
+	deadline := time.Now().Add(mergePollTimeout)
+
+	for {
+		buf, err := c.do("GET", taskURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var status mergeTaskStatus
+
+		err = json.NewDecoder(buf).Decode(&status)
+		if err != nil {
+			return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+		}
+
+		switch status.Status {
+		case "SUCCESS":
+			if status.Result == nil {
+				return nil, fmt.Errorf("bitbucket/client: merge task %s succeeded without result", taskURL)
+			}
+
+			return status.Result, nil
+		case "PENDING":
+		default:
+			return nil, fmt.Errorf("bitbucket/client: merge task %s has unexpected status %s", taskURL, status.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("bitbucket/client: merge task %s not finished within %s", taskURL, mergePollTimeout)
+		}
+
+		time.Sleep(mergePollInterval)
+	}
+}
+
+func (c Client) pullRequestTransition(id string, transition string, body interface{}) (*PullRequestEntity, error) {
+	url := c.APIURL(pullRequestsEndpoint, id, transition)
+
+	buf, err := c.do("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePullRequest(buf)
+}
+
+func decodePullRequest(buf *bytes.Buffer) (*PullRequestEntity, error) {
+	var pr PullRequestEntity
+
+	err := json.NewDecoder(buf).Decode(&pr)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &pr, nil
+}
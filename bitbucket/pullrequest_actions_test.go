@@ -0,0 +1,100 @@
+package bitbucket
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirectTransport sends all requests to the test server, keeping their paths
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	target, _ := url.Parse(srv.URL)
+
+	return NewClient("workspace", "slug", &Auth{AccessToken: "token"}, &http.Client{Transport: redirectTransport{target}})
+}
+
+func TestMergePullRequestAccepted(t *testing.T) {
+	mergePollInterval = time.Millisecond
+	defer func() { mergePollInterval = 2 * time.Second }()
+
+	const taskPath = "/2.0/repositories/workspace/slug/pullrequests/7/merge/task-status/42"
+
+	polls := 0
+
+	cli := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/pullrequests/7/merge"):
+			w.Header().Set("Location", "https://api.bitbucket.org"+taskPath)
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == "GET" && r.URL.Path == taskPath:
+			polls++
+			if polls < 3 {
+				fmt.Fprint(w, `{"task_status": "PENDING"}`)
+				return
+			}
+			fmt.Fprint(w, `{"task_status": "SUCCESS", "merge_result": {"id": 7, "state": "MERGED", "merge_commit": {"hash": "abc123"}}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	pr, err := cli.MergePullRequest("7", &MergeRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.State != MergedPullRequestState || pr.MergeCommit == nil || pr.MergeCommit.Hash != "abc123" {
+		t.Errorf("unexpected merged PR %+v", pr)
+	}
+
+	if polls != 3 {
+		t.Errorf("expected 3 polls, got %d", polls)
+	}
+}
+
+func TestMergePullRequestAcceptedFailure(t *testing.T) {
+	cli := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Header().Set("Location", "https://api.bitbucket.org/2.0/task-status/42")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"type": "error", "error": {"message": "Merge conflict"}}`)
+	})
+
+	_, err := cli.MergePullRequest("7", &MergeRequest{})
+	if err == nil || !strings.Contains(err.Error(), "Merge conflict") {
+		t.Errorf("expected merge conflict error, got %v", err)
+	}
+}
+
+func TestMergePullRequestAcceptedWithoutLink(t *testing.T) {
+	cli := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	_, err := cli.MergePullRequest("7", &MergeRequest{})
+	if err == nil {
+		t.Error("expected error for merge accepted without task status link")
+	}
+}
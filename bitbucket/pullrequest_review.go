@@ -0,0 +1,123 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// TaskState of PR task
+type TaskState string
+
+const (
+	// UnresolvedTaskState as task is still to be done
+	UnresolvedTaskState TaskState = "UNRESOLVED"
+
+	// ResolvedTaskState as task is done
+	ResolvedTaskState TaskState = "RESOLVED"
+)
+
+// TaskComment links task to PR comment
+type TaskComment struct {
+	ID int `json:"id"`
+}
+
+// TaskEntity of PR
+type TaskEntity struct {
+	ID      int            `json:"id,omitempty"`
+	Content CommentContent `json:"content"`
+	State   TaskState      `json:"state,omitempty"`
+	Comment *TaskComment   `json:"comment,omitempty"`
+}
+
+// updateReviewersRequest body of PR update. Title is required by API even if not changed
+type updateReviewersRequest struct {
+	Title     string          `json:"title"`
+	Reviewers []UserReference `json:"reviewers"`
+}
+
+// UpdatePullRequestReviewers replaces reviewers of PR
+func (c Client) UpdatePullRequestReviewers(id string, title string, reviewers []UserReference) (*PullRequestEntity, error) {
+	buf, err := c.do("PUT", c.APIURL(pullRequestsEndpoint, id), &updateReviewersRequest{
+		Title:     title,
+		Reviewers: reviewers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequestEntity
+
+	err = json.NewDecoder(buf).Decode(&pr)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &pr, nil
+}
+
+// GetPullRequestTasks fetches all tasks of PR. Results are autopaged
+func (c Client) GetPullRequestTasks(id string) ([]TaskEntity, error) {
+	values := make([]TaskEntity, 0)
+
+	url := c.APIURL(pullRequestsEndpoint, id, "tasks")
+	url = fmt.Sprintf("%s?pagelen=%d", url, 100)
+
+	for len(url) > 0 {
+		resp, err := c.getPage(url)
+		if err != nil {
+			return nil, err
+		}
+
+		var valuesPage []TaskEntity
+
+		err = json.Unmarshal([]byte(resp.Values), &valuesPage)
+		if err != nil {
+			return nil, fmt.Errorf("bitbucket/client: unmarshal paged values: %w", err)
+		}
+
+		values = append(values, valuesPage...)
+		url = resp.Next
+	}
+
+	return values, nil
+}
+
+// CreatePullRequestTask with content, optionally linked to comment
+func (c Client) CreatePullRequestTask(id string, content string, commentID int) (*TaskEntity, error) {
+	task := TaskEntity{
+		Content: CommentContent{Raw: content},
+	}
+
+	if commentID > 0 {
+		task.Comment = &TaskComment{ID: commentID}
+	}
+
+	return c.sendTask("POST", c.APIURL(pullRequestsEndpoint, id, "tasks"), &task)
+}
+
+// ResolvePullRequestTask marks task as done
+func (c Client) ResolvePullRequestTask(id string, task TaskEntity) (*TaskEntity, error) {
+	url := c.APIURL(pullRequestsEndpoint, id, "tasks", strconv.Itoa(task.ID))
+
+	return c.sendTask("PUT", url, &TaskEntity{
+		Content: task.Content,
+		State:   ResolvedTaskState,
+	})
+}
+
+func (c Client) sendTask(method, url string, task *TaskEntity) (*TaskEntity, error) {
+	buf, err := c.do(method, url, task)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent TaskEntity
+
+	err = json.NewDecoder(buf).Decode(&sent)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucket/client: decode %s: %w", buf.Bytes(), err)
+	}
+
+	return &sent, nil
+}
@@ -11,7 +11,7 @@ func TestGetPullRequests(t *testing.T) {
 		Password: "",
 	}
 
-	cli := NewClient("n7mobile", "n7mobile", &auth)
+	cli := NewClient("n7mobile", "n7mobile", &auth, nil)
 
 	prs, err := cli.GetPullRequestsPaged()
 	if err != nil {
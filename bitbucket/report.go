@@ -0,0 +1,121 @@
+package bitbucket
+
+import "net/url"
+
+// ReportResult indicates overall outcome of Code Insights report
+type ReportResult string
+
+const (
+	// PassedReportResult as analysis found no issues
+	PassedReportResult ReportResult = "PASSED"
+
+	// FailedReportResult as analysis found issues
+	FailedReportResult ReportResult = "FAILED"
+
+	// PendingReportResult as analysis is still running
+	PendingReportResult ReportResult = "PENDING"
+)
+
+// ReportType categorizes Code Insights report
+type ReportType string
+
+const (
+	SecurityReportType ReportType = "SECURITY"
+	CoverageReportType ReportType = "COVERAGE"
+	TestReportType     ReportType = "TEST"
+	BugReportType      ReportType = "BUG"
+)
+
+// ReportDataType indicates how value of report data field is presented
+type ReportDataType string
+
+const (
+	BooleanReportDataType    ReportDataType = "BOOLEAN"
+	DateReportDataType       ReportDataType = "DATE"
+	DurationReportDataType   ReportDataType = "DURATION"
+	LinkReportDataType       ReportDataType = "LINK"
+	NumberReportDataType     ReportDataType = "NUMBER"
+	PercentageReportDataType ReportDataType = "PERCENTAGE"
+	TextReportDataType       ReportDataType = "TEXT"
+)
+
+// ReportData is a single typed field shown in Code Insights report, ie. coverage percentage
+type ReportData struct {
+	Title string         `json:"title"`
+	Type  ReportDataType `json:"type"`
+	Value interface{}    `json:"value"`
+}
+
+// Report of Code Insights attached to commit
+type Report struct {
+	Title      string       `json:"title"`
+	Details    string       `json:"details,omitempty"`
+	ReportType ReportType   `json:"report_type,omitempty"`
+	Reporter   string       `json:"reporter,omitempty"`
+	Link       string       `json:"link,omitempty"`
+	Result     ReportResult `json:"result,omitempty"`
+	Data       []ReportData `json:"data,omitempty"`
+}
+
+// CreateReport on the commit. Report previously created with the same id is replaced
+func (c Client) CreateReport(commitHash string, id string, report *Report) error {
+	escapedID := url.PathEscape(id)
+	url := c.APIURL(commitEndpoint, commitHash, "reports", escapedID)
+
+	_, err := c.do("PUT", url, report)
+
+	return err
+}
+
+// AnnotationType categorizes single finding of Code Insights report
+type AnnotationType string
+
+const (
+	VulnerabilityAnnotationType AnnotationType = "VULNERABILITY"
+	CodeSmellAnnotationType     AnnotationType = "CODE_SMELL"
+	BugAnnotationType           AnnotationType = "BUG"
+)
+
+// AnnotationSeverity of single finding of Code Insights report
+type AnnotationSeverity string
+
+const (
+	LowAnnotationSeverity      AnnotationSeverity = "LOW"
+	MediumAnnotationSeverity   AnnotationSeverity = "MEDIUM"
+	HighAnnotationSeverity     AnnotationSeverity = "HIGH"
+	CriticalAnnotationSeverity AnnotationSeverity = "CRITICAL"
+)
+
+// Annotation is a single finding of Code Insights report, presented inline in PR diff when path and line are set
+type Annotation struct {
+	ExternalID     string             `json:"external_id"`
+	AnnotationType AnnotationType     `json:"annotation_type"`
+	Summary        string             `json:"summary"`
+	Details        string             `json:"details,omitempty"`
+	Severity       AnnotationSeverity `json:"severity,omitempty"`
+	Path           string             `json:"path,omitempty"`
+	Line           int                `json:"line,omitempty"`
+}
+
+// MaxAnnotationsPerRequest is a limit of annotations uploaded by single API call
+const MaxAnnotationsPerRequest = 100
+
+// CreateAnnotations of the report created on the commit. Annotations are uploaded in batches of API limit
+func (c Client) CreateAnnotations(commitHash string, reportID string, annotations []Annotation) error {
+	escapedID := url.PathEscape(reportID)
+	url := c.APIURL(commitEndpoint, commitHash, "reports", escapedID, "annotations")
+
+	for start := 0; start < len(annotations); start += MaxAnnotationsPerRequest {
+		end := start + MaxAnnotationsPerRequest
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+
+		_, err := c.do("POST", url, annotations[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
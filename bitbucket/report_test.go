@@ -0,0 +1,36 @@
+package bitbucket
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReportIDEscaped(t *testing.T) {
+	paths := make([]string, 0)
+
+	cli := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Write([]byte("{}"))
+	})
+
+	err := cli.CreateReport("abc123", "lint/go?v=1#x", &Report{Title: "Lint"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.CreateAnnotations("abc123", "lint/go?v=1#x", []Annotation{{ExternalID: "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/2.0/repositories/workspace/slug/commit/abc123/reports/lint%2Fgo%3Fv=1%23x",
+		"/2.0/repositories/workspace/slug/commit/abc123/reports/lint%2Fgo%3Fv=1%23x/annotations",
+	}
+
+	for i, path := range expected {
+		if i >= len(paths) || paths[i] != path {
+			t.Errorf("expected request to %s, got %v", path, paths)
+		}
+	}
+}
@@ -2,4 +2,7 @@ module github.com/n7mobile/concourse-bitbucket-pr
 
 go 1.15
 
-require github.com/libgit2/git2go/v31 v31.4.10
\ No newline at end of file
+require (
+	github.com/libgit2/git2go/v31 v31.4.10
+	golang.org/x/crypto v0.0.0-20201203163018-be400aefbc4c
+)
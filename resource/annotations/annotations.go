@@ -0,0 +1,122 @@
+// Package annotations parses outputs of test runners and linters into Code Insights annotations
+package annotations
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+const (
+	maxSummaryLength = 450
+	maxDetailsLength = 2000
+)
+
+// parser reads single tool output
+type parser func(r io.Reader) ([]bitbucket.Annotation, error)
+
+var parsers = map[models.AnnotationFormat]parser{
+	models.JUnitAnnotationFormat:        parseJUnit,
+	models.CheckstyleAnnotationFormat:   parseCheckstyle,
+	models.SARIFAnnotationFormat:        parseSARIF,
+	models.GoVetAnnotationFormat:        parseGoVet,
+	models.GolangCILintAnnotationFormat: parseGolangCILint,
+}
+
+// Parse tool output of the format. Paths are made relative to the repo root by removing stripPrefix
+func Parse(format models.AnnotationFormat, stripPrefix string, r io.Reader) ([]bitbucket.Annotation, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("resource/annotations: format %s not supported", format)
+	}
+
+	annotations, err := parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("resource/annotations: parse %s: %w", format, err)
+	}
+
+	for i := range annotations {
+		a := &annotations[i]
+
+		a.Path = relativePath(a.Path, stripPrefix)
+		a.Summary = truncate(a.Summary, maxSummaryLength)
+		a.Details = truncate(a.Details, maxDetailsLength)
+		a.ExternalID = externalID(format, a)
+	}
+
+	return annotations, nil
+}
+
+// Limit annotations to max, dropping the least severe ones. Duplicates are removed as they share external id
+func Limit(annotations []bitbucket.Annotation, max int) []bitbucket.Annotation {
+	unique := make([]bitbucket.Annotation, 0, len(annotations))
+	seen := make(map[string]bool)
+
+	for _, a := range annotations {
+		if seen[a.ExternalID] {
+			continue
+		}
+
+		seen[a.ExternalID] = true
+		unique = append(unique, a)
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return severityRank(unique[i].Severity) > severityRank(unique[j].Severity)
+	})
+
+	if len(unique) > max {
+		unique = unique[:max]
+	}
+
+	return unique
+}
+
+func severityRank(s bitbucket.AnnotationSeverity) int {
+	switch s {
+	case bitbucket.CriticalAnnotationSeverity:
+		return 3
+	case bitbucket.HighAnnotationSeverity:
+		return 2
+	case bitbucket.MediumAnnotationSeverity:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// externalID identifies annotation within report, stable across builds of the same findings
+func externalID(format models.AnnotationFormat, a *bitbucket.Annotation) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s", format, a.Path, a.Line, a.Summary)
+
+	return string(format) + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func relativePath(path, stripPrefix string) string {
+	path = strings.TrimPrefix(path, "file://")
+	path = filepath.ToSlash(path)
+
+	if len(stripPrefix) > 0 {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(filepath.ToSlash(stripPrefix), "/")+"/")
+	}
+
+	return strings.TrimPrefix(path, "./")
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(strings.TrimSpace(s))
+
+	if len(runes) <= max {
+		return string(runes)
+	}
+
+	return string(runes[:max-3]) + "..."
+}
@@ -0,0 +1,88 @@
+package annotations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		format   models.AnnotationFormat
+		input    string
+		expected bitbucket.Annotation
+	}{
+		{
+			models.JUnitAnnotationFormat,
+			`<testsuites><testsuite name="s">
+				<testcase classname="pkg.Suite" name="ok"/>
+				<testcase classname="pkg.Suite" name="fails" file="/build/repo/pkg/suite.py" line="12"><failure message="expected 1">trace</failure></testcase>
+			</testsuite></testsuites>`,
+			bitbucket.Annotation{AnnotationType: bitbucket.BugAnnotationType, Summary: "pkg.Suite.fails: expected 1", Details: "trace",
+				Severity: bitbucket.HighAnnotationSeverity, Path: "pkg/suite.py", Line: 12},
+		},
+		{
+			models.CheckstyleAnnotationFormat,
+			`<checkstyle><file name="/build/repo/src/A.java"><error line="3" severity="warning" message="unused import" source="UnusedImports"/></file></checkstyle>`,
+			bitbucket.Annotation{AnnotationType: bitbucket.CodeSmellAnnotationType, Summary: "unused import", Details: "UnusedImports",
+				Severity: bitbucket.MediumAnnotationSeverity, Path: "src/A.java", Line: 3},
+		},
+		{
+			models.SARIFAnnotationFormat,
+			`{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "scanner", "rules": [{"id": "G101", "properties": {"tags": ["security"]}}]}},
+				"results": [{"ruleId": "G101", "level": "error", "message": {"text": "hardcoded credentials"},
+				"locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///build/repo/main.go"}, "region": {"startLine": 7}}}]}]}]}`,
+			bitbucket.Annotation{AnnotationType: bitbucket.VulnerabilityAnnotationType, Summary: "G101: hardcoded credentials", Details: "hardcoded credentials",
+				Severity: bitbucket.HighAnnotationSeverity, Path: "main.go", Line: 7},
+		},
+		{
+			models.GoVetAnnotationFormat,
+			"# example.com/pkg\n{\n\t\"example.com/pkg\": {\n\t\t\"printf\": [{\"posn\": \"/build/repo/pkg/a.go:5:2\", \"message\": \"wrong verb\"}]\n\t}\n}\n",
+			bitbucket.Annotation{AnnotationType: bitbucket.CodeSmellAnnotationType, Summary: "printf: wrong verb",
+				Severity: bitbucket.MediumAnnotationSeverity, Path: "pkg/a.go", Line: 5},
+		},
+		{
+			models.GolangCILintAnnotationFormat,
+			`{"Issues": [{"FromLinter": "errcheck", "Text": "error not checked", "Pos": {"Filename": "pkg/a.go", "Line": 9}}]}`,
+			bitbucket.Annotation{AnnotationType: bitbucket.CodeSmellAnnotationType, Summary: "errcheck: error not checked",
+				Severity: bitbucket.MediumAnnotationSeverity, Path: "pkg/a.go", Line: 9},
+		},
+	}
+
+	for _, c := range cases {
+		annotations, err := Parse(c.format, "/build/repo", strings.NewReader(c.input))
+		if err != nil {
+			t.Fatalf("%s: %s", c.format, err)
+		}
+
+		if len(annotations) != 1 {
+			t.Fatalf("%s: expected single annotation, got %+v", c.format, annotations)
+		}
+
+		actual := annotations[0]
+		if len(actual.ExternalID) == 0 {
+			t.Errorf("%s: external id is empty", c.format)
+		}
+
+		actual.ExternalID = ""
+		if actual != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", c.format, c.expected, actual)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	annotations := []bitbucket.Annotation{
+		{ExternalID: "a", Severity: bitbucket.LowAnnotationSeverity},
+		{ExternalID: "b", Severity: bitbucket.HighAnnotationSeverity},
+		{ExternalID: "b", Severity: bitbucket.HighAnnotationSeverity},
+		{ExternalID: "c", Severity: bitbucket.MediumAnnotationSeverity},
+	}
+
+	limited := Limit(annotations, 2)
+	if len(limited) != 2 || limited[0].ExternalID != "b" || limited[1].ExternalID != "c" {
+		t.Errorf("expected most severe unique annotations, got %+v", limited)
+	}
+}
@@ -0,0 +1,124 @@
+package annotations
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+)
+
+// goVetDiagnostic as written by `go vet -json`, keyed by package and analyzer
+type goVetDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// parseGoVet annotates diagnostics of stream of JSON objects, one per package, interleaved with `# package` lines
+func parseGoVet(r io.Reader) ([]bitbucket.Annotation, error) {
+	var buf bytes.Buffer
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "#") {
+			continue
+		}
+
+		buf.Write(scanner.Bytes())
+		buf.WriteByte('\n')
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	annotations := make([]bitbucket.Annotation, 0)
+	decoder := json.NewDecoder(&buf)
+
+	for decoder.More() {
+		var packages map[string]map[string]json.RawMessage
+
+		err := decoder.Decode(&packages)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, analyzers := range packages {
+			for analyzer, raw := range analyzers {
+				var diagnostics []goVetDiagnostic
+
+				// analyzer failures are reported as an object, not a list of diagnostics
+				if json.Unmarshal(raw, &diagnostics) != nil {
+					continue
+				}
+
+				for _, d := range diagnostics {
+					path, line := splitPosition(d.Posn)
+
+					annotations = append(annotations, bitbucket.Annotation{
+						AnnotationType: bitbucket.CodeSmellAnnotationType,
+						Summary:        analyzer + ": " + d.Message,
+						Severity:       bitbucket.MediumAnnotationSeverity,
+						Path:           path,
+						Line:           line,
+					})
+				}
+			}
+		}
+	}
+
+	return annotations, nil
+}
+
+// splitPosition of file:line:column format
+func splitPosition(posn string) (string, int) {
+	parts := strings.Split(posn, ":")
+	if len(parts) < 3 {
+		return posn, 0
+	}
+
+	line, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		return posn, 0
+	}
+
+	return strings.Join(parts[:len(parts)-2], ":"), line
+}
+
+type golangCILintResult struct {
+	Issues []struct {
+		FromLinter string `json:"FromLinter"`
+		Text       string `json:"Text"`
+		Severity   string `json:"Severity"`
+		Pos        struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+		} `json:"Pos"`
+	} `json:"Issues"`
+}
+
+func parseGolangCILint(r io.Reader) ([]bitbucket.Annotation, error) {
+	var result golangCILintResult
+
+	err := json.NewDecoder(r).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]bitbucket.Annotation, 0, len(result.Issues))
+
+	for _, issue := range result.Issues {
+		annotations = append(annotations, bitbucket.Annotation{
+			AnnotationType: bitbucket.CodeSmellAnnotationType,
+			Summary:        issue.FromLinter + ": " + issue.Text,
+			Severity:       levelSeverity(issue.Severity),
+			Path:           issue.Pos.Filename,
+			Line:           issue.Pos.Line,
+		})
+	}
+
+	return annotations, nil
+}
@@ -0,0 +1,94 @@
+package annotations
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+)
+
+// sarifLog is a subset of SARIF 2.1 needed for annotations
+type sarifLog struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []struct {
+					ID         string `json:"id"`
+					Properties struct {
+						Tags []string `json:"tags"`
+					} `json:"properties"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine int `json:"startLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+// parseSARIF annotates results of all runs. Results of rules tagged as security are reported as vulnerabilities
+func parseSARIF(r io.Reader) ([]bitbucket.Annotation, error) {
+	var log sarifLog
+
+	err := json.NewDecoder(r).Decode(&log)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]bitbucket.Annotation, 0)
+
+	for _, run := range log.Runs {
+		security := make(map[string]bool)
+
+		for _, rule := range run.Tool.Driver.Rules {
+			for _, tag := range rule.Properties.Tags {
+				if strings.EqualFold(tag, "security") {
+					security[rule.ID] = true
+				}
+			}
+		}
+
+		for _, result := range run.Results {
+			annotation := bitbucket.Annotation{
+				AnnotationType: bitbucket.CodeSmellAnnotationType,
+				Summary:        firstLine(result.Message.Text),
+				Details:        result.Message.Text,
+				Severity:       levelSeverity(result.Level),
+			}
+
+			if len(result.RuleID) > 0 {
+				annotation.Summary = result.RuleID + ": " + annotation.Summary
+			}
+
+			if security[result.RuleID] {
+				annotation.AnnotationType = bitbucket.VulnerabilityAnnotationType
+			}
+
+			if len(result.Locations) > 0 {
+				location := result.Locations[0].PhysicalLocation
+				annotation.Path = location.ArtifactLocation.URI
+				annotation.Line = location.Region.StartLine
+			}
+
+			annotations = append(annotations, annotation)
+		}
+	}
+
+	return annotations, nil
+}
@@ -0,0 +1,146 @@
+package annotations
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+)
+
+// junitTestCase as written by most of test runners. File and line are extensions, present ie. in pytest output
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      string        `xml:"line,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnit annotates failed and errored test cases, found at any depth of nested test suites
+func parseJUnit(r io.Reader) ([]bitbucket.Annotation, error) {
+	annotations := make([]bitbucket.Annotation, 0)
+	decoder := xml.NewDecoder(r)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "testcase" {
+			continue
+		}
+
+		var testCase junitTestCase
+
+		err = decoder.DecodeElement(&testCase, &start)
+		if err != nil {
+			return nil, err
+		}
+
+		failure := testCase.Failure
+		if failure == nil {
+			failure = testCase.Error
+		}
+		if failure == nil {
+			continue
+		}
+
+		name := testCase.Name
+		if len(testCase.Classname) > 0 {
+			name = testCase.Classname + "." + name
+		}
+
+		message := failure.Message
+		if len(message) == 0 {
+			message = failure.Type
+		}
+
+		line, _ := strconv.Atoi(testCase.Line)
+
+		annotations = append(annotations, bitbucket.Annotation{
+			AnnotationType: bitbucket.BugAnnotationType,
+			Summary:        fmt.Sprintf("%s: %s", name, firstLine(message)),
+			Details:        failure.Text,
+			Severity:       bitbucket.HighAnnotationSeverity,
+			Path:           testCase.File,
+			Line:           line,
+		})
+	}
+
+	return annotations, nil
+}
+
+type checkstyleResult struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+func parseCheckstyle(r io.Reader) ([]bitbucket.Annotation, error) {
+	var result checkstyleResult
+
+	err := xml.NewDecoder(r).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]bitbucket.Annotation, 0)
+
+	for _, f := range result.Files {
+		for _, e := range f.Errors {
+			annotations = append(annotations, bitbucket.Annotation{
+				AnnotationType: bitbucket.CodeSmellAnnotationType,
+				Summary:        e.Message,
+				Details:        e.Source,
+				Severity:       levelSeverity(e.Severity),
+				Path:           f.Name,
+				Line:           e.Line,
+			})
+		}
+	}
+
+	return annotations, nil
+}
+
+// levelSeverity maps severity levels common to linters
+func levelSeverity(level string) bitbucket.AnnotationSeverity {
+	switch strings.ToLower(level) {
+	case "error":
+		return bitbucket.HighAnnotationSeverity
+	case "info", "note", "none", "ignore":
+		return bitbucket.LowAnnotationSeverity
+	default:
+		return bitbucket.MediumAnnotationSeverity
+	}
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+
+	return s
+}
@@ -40,12 +40,12 @@ func (cmd *CheckCommand) Run(req models.CheckRequest) ([]models.Version, error)
 		return nil, fmt.Errorf("resource/check: source invalid: %w", err)
 	}
 
-	auth := bitbucket.Auth{
-		Username: req.Source.Username,
-		Password: req.Source.Password,
+	trans, err := newTransport(req.Source)
+	if err != nil {
+		return nil, fmt.Errorf("resource/check: transport: %w", err)
 	}
 
-	client := bitbucket.NewClient(req.Source.Workspace, req.Source.Slug, &auth)
+	client := trans.BitbucketClient()
 	preqs, err := client.GetPullRequestsPaged()
 	if err != nil {
 		return nil, fmt.Errorf("resource/check: paged prs: %w", err)
@@ -53,8 +53,8 @@ func (cmd *CheckCommand) Run(req models.CheckRequest) ([]models.Version, error)
 
 	destination := "/tmp/" + req.Source.Slug
 
-	url := bitbucket.NewClient(req.Source.Workspace, req.Source.Slug, &auth).RepoURL()
-	repo, err := cmd.gitBareClone(req.Source.Username, req.Source.Password, url, destination)
+	url := client.RepoURL()
+	repo, err := cmd.gitBareClone(trans.FetchOptions(url), url, destination)
 	if err != nil {
 		return nil, fmt.Errorf("resource/check: repo clone: %w", err)
 	}
@@ -104,26 +104,12 @@ func (cmd *CheckCommand) Run(req models.CheckRequest) ([]models.Version, error)
 	return versions, nil
 }
 
-func (cmd CheckCommand) gitBareClone(user, pass string, url string, destination string) (*git.Repository, error) {
-	creds, err := git.NewCredentialUserpassPlaintext(user, pass)
-	if err != nil {
-		return nil, fmt.Errorf("resource/check: git creds: %w", err)
-	}
-
+func (cmd CheckCommand) gitBareClone(fetchOpts *git.FetchOptions, url string, destination string) (*git.Repository, error) {
 	cmd.Logger.Debugf("resource/check: clone history from repo %s", url)
 
 	opts := git.CloneOptions{
-		FetchOptions: &git.FetchOptions{
-			RemoteCallbacks: git.RemoteCallbacks{
-				CredentialsCallback: func(url, username_from_url string, allowed_types git.CredentialType) (*git.Credential, error) {
-					return creds, nil
-				},
-				CertificateCheckCallback: func(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
-					return git.ErrorCodeOK
-				},
-			},
-		},
-		Bare: true,
+		FetchOptions: fetchOpts,
+		Bare:         true,
 	}
 
 	repo, err := git.Clone(url, destination, &opts)
@@ -0,0 +1,143 @@
+package resource
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+)
+
+// downloadArchive of the commit and extract it into destination
+func (cmd *InCommand) downloadArchive(client *bitbucket.Client, commitHash, destination string) error {
+	tmp, err := ioutil.TempFile("", "bitbucket-pr-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("resource/in: archive temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cmd.Logger.Debugf("resource/in: download archive of %s", commitHash)
+
+	err = client.DownloadArchive(commitHash, tmp)
+	if err != nil {
+		return fmt.Errorf("resource/in: archive download: %w", err)
+	}
+
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("resource/in: archive rewind: %w", err)
+	}
+
+	cmd.Logger.Debugf("resource/in: extract archive into %s", destination)
+
+	err = extractTarGz(tmp, destination)
+	if err != nil {
+		return fmt.Errorf("resource/in: archive extract: %w", err)
+	}
+
+	return nil
+}
+
+// extractTarGz into destination, stripping top level directory added by BitBucket
+func extractTarGz(r io.Reader, destination string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		name := header.Name
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[i+1:]
+		} else {
+			continue
+		}
+
+		if len(name) == 0 {
+			continue
+		}
+
+		path := filepath.Join(destination, name)
+		if !strings.HasPrefix(path, filepath.Clean(destination)+string(os.PathSeparator)) {
+			return fmt.Errorf("illegal path in archive: %s", header.Name)
+		}
+
+		err = checkNoSymlinks(destination, name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg, tar.TypeRegA:
+			err = extractFile(tr, path, os.FileMode(header.Mode))
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, path)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, path string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+
+	return err
+}
+
+// checkNoSymlinks among existing components of relative path, so entries are never written through symlink
+// extracted earlier, which may point outside of destination. Symlinks themselves are kept like git does
+func checkNoSymlinks(destination, name string) error {
+	path := destination
+
+	for _, part := range strings.Split(filepath.Clean(name), string(os.PathSeparator)) {
+		path = filepath.Join(path, part)
+
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("illegal path in archive, %s is a symlink", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package resource
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	header  tar.Header
+	content string
+}
+
+func tarGz(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		h := e.header
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(e.content))
+		}
+		if h.Mode == 0 && h.Typeflag != tar.TypeXGlobalHeader {
+			h.Mode = 0644
+		}
+
+		err := tw.WriteHeader(&h)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = tw.Write([]byte(e.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func TestExtractTarGz(t *testing.T) {
+	dest := tempDir(t)
+
+	archive := tarGz(t,
+		tarEntry{header: tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "abc123"}}},
+		tarEntry{header: tar.Header{Typeflag: tar.TypeDir, Name: "repo-abc123/", Mode: 0755}},
+		tarEntry{header: tar.Header{Typeflag: tar.TypeReg, Name: "repo-abc123/README.md"}, content: "readme"},
+		tarEntry{header: tar.Header{Typeflag: tar.TypeReg, Name: "repo-abc123/src/main.go", Mode: 0755}, content: "package main"},
+		tarEntry{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "repo-abc123/docs", Linkname: "README.md"}},
+	)
+
+	err := extractTarGz(archive, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{"README.md": "readme", "src/main.go": "package main", "docs": "readme"}
+	for name, expected := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		} else if string(content) != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, content)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dest, "src/main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected executable mode kept, got %s", info.Mode())
+	}
+
+	for _, name := range []string{"repo-abc123", "pax_global_header"} {
+		if _, err := os.Lstat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not extracted, got %v", name, err)
+		}
+	}
+}
+
+func TestExtractTarGzTraversal(t *testing.T) {
+	cases := map[string][]tarEntry{
+		"parent dir": {
+			{header: tar.Header{Typeflag: tar.TypeReg, Name: "repo-abc123/../../evil"}, content: "evil"},
+		},
+		"absolute path": {
+			{header: tar.Header{Typeflag: tar.TypeReg, Name: "/repo-abc123/../../evil"}, content: "evil"},
+		},
+		"through symlink": {
+			{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "repo-abc123/link", Linkname: "../outside"}},
+			{header: tar.Header{Typeflag: tar.TypeReg, Name: "repo-abc123/link/evil"}, content: "evil"},
+		},
+		"overwrite symlink": {
+			{header: tar.Header{Typeflag: tar.TypeSymlink, Name: "repo-abc123/link", Linkname: "../outside/evil"}},
+			{header: tar.Header{Typeflag: tar.TypeReg, Name: "repo-abc123/link"}, content: "evil"},
+		},
+	}
+
+	for name, entries := range cases {
+		t.Run(name, func(t *testing.T) {
+			root := tempDir(t)
+			dest := filepath.Join(root, "dest")
+			outside := filepath.Join(root, "outside")
+
+			for _, dir := range []string{dest, outside} {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := extractTarGz(tarGz(t, entries...), dest)
+			if err == nil {
+				t.Error("expected illegal path error")
+			}
+
+			for _, path := range []string{filepath.Join(root, "evil"), filepath.Join(outside, "evil")} {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("expected nothing written to %s, got %v", path, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package resource
+
+import (
+	"fmt"
+
+	git "github.com/libgit2/git2go/v31"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// localBranchName resolves naming option into branch name. Empty name means detached HEAD
+func localBranchName(option models.LocalBranch, id string, sourceBranch *string) (string, error) {
+	switch option {
+	case models.SourceLocalBranch:
+		if sourceBranch == nil {
+			return "", fmt.Errorf("resource/in: source branch of PR %s is unknown", id)
+		}
+
+		return *sourceBranch, nil
+	case models.PullRequestLocalBranch:
+		return "pr-" + id, nil
+	}
+
+	return "", nil
+}
+
+// gitCheckoutLocalBranch creates local branch at PR head, tracking upstream if known, and moves HEAD onto it
+func (cmd InCommand) gitCheckoutLocalBranch(commit *git.Commit, name string, upstream *string) error {
+	repo := commit.Owner()
+
+	cmd.Logger.Debugf("resource/in: local branch '%s' at %s", name, commit.Id().String())
+
+	branch, err := repo.CreateBranch(name, commit, true)
+	if err != nil {
+		return fmt.Errorf("resource/in: create branch %s: %w", name, err)
+	}
+
+	if upstream != nil {
+		err = branch.SetUpstream("origin/" + *upstream)
+		if err != nil {
+			return fmt.Errorf("resource/in: set upstream of %s: %w", name, err)
+		}
+	}
+
+	err = repo.SetHead(branch.Reference.Name())
+	if err != nil {
+		return fmt.Errorf("resource/in: set head to %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// gitTrackRemoteBranch creates or resets local branch to the tip of its remote counterpart
+func (cmd InCommand) gitTrackRemoteBranch(repo *git.Repository, name string) error {
+	remoteBranch, err := repo.LookupBranch("origin/"+name, git.BranchRemote)
+	if err != nil {
+		return fmt.Errorf("resource/in: lookup remote branch %s: %w", name, err)
+	}
+
+	commit, err := repo.LookupCommit(remoteBranch.Target())
+	if err != nil {
+		return fmt.Errorf("resource/in: lookup commit of %s: %w", name, err)
+	}
+
+	cmd.Logger.Debugf("resource/in: local branch '%s' at %s", name, commit.Id().String())
+
+	branch, err := repo.CreateBranch(name, commit, true)
+	if err != nil {
+		return fmt.Errorf("resource/in: create branch %s: %w", name, err)
+	}
+
+	err = branch.SetUpstream("origin/" + name)
+	if err != nil {
+		return fmt.Errorf("resource/in: set upstream of %s: %w", name, err)
+	}
+
+	return nil
+}
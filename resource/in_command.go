@@ -30,6 +30,13 @@ func (cmd *InCommand) Run(destination string, req models.InRequest) (*models.InR
 		return nil, fmt.Errorf("resource/in: version validation: %w", err)
 	}
 
+	req.Params = req.Params.WithDefaults()
+
+	err = req.Params.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: params validation: %w", err)
+	}
+
 	cmd.Logger.Debugf("resource/in: Creating destination directory at %s", destination)
 
 	path, _ := filepath.Split(destination)
@@ -38,32 +45,116 @@ func (cmd *InCommand) Run(destination string, req models.InRequest) (*models.InR
 		return nil, fmt.Errorf("resource/in: creating destination: %w", err)
 	}
 
-	cmd.Logger.Debugf("resource/in: repo checkout...")
+	trans, err := newTransport(req.Source)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: transport: %w", err)
+	}
 
-	auth := bitbucket.Auth{
-		Username: req.Source.Username,
-		Password: req.Source.Password,
+	client := trans.BitbucketClient()
+
+	if req.Params.SkipDownload || req.Params.Format == models.ArchiveInFormat {
+		return cmd.runWithoutClone(destination, req, client)
 	}
 
-	client := bitbucket.NewClient(req.Source.Workspace, req.Source.Slug, &auth)
+	cmd.Logger.Debugf("resource/in: repo checkout...")
+
 	url := client.RepoURL()
 
-	commit, err := cmd.gitCheckoutRef(req.Source.Username, req.Source.Password, url, req.Version.Ref, destination)
+	commit, err := cmd.gitCheckoutRef(trans.FetchOptions(url), url, req.Version.Ref, destination)
 	if err != nil {
 		return nil, fmt.Errorf("resource/in: gitCheckoutRef: %w", err)
 	}
 
 	cmd.Logger.Debugf("resource/in: Checkout succeeded")
 
-	if req.Source.RecurseSubmodules {
+	if req.Params.VerifySignatures {
+		cmd.Logger.Debugf("resource/in: verify signature of %s", commit.Id().String())
+
+		err = verifyCommitSignature(commit, req.Params.TrustedKeys)
+		if err != nil {
+			return nil, fmt.Errorf("resource/in: signature verification: %w", err)
+		}
+	}
+
+	if len(req.Source.GitConfig) > 0 {
+		err = cmd.gitApplyConfig(commit.Owner(), req.Source.GitConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	submodules := models.Submodules{All: req.Source.RecurseSubmodules}
+	if req.Params.Submodules != nil {
+		submodules = *req.Params.Submodules
+	}
+
+	if !submodules.Empty() {
 		cmd.Logger.Debugf("resource/in: submodules update")
 
-		cmd.gitUpdateSubmodules(req.Source.Username, req.Source.Password, commit.Owner())
+		err = cmd.gitUpdateSubmodules(trans, url, commit.Owner(), submoduleOptions{
+			submodules:  submodules,
+			recursive:   req.Params.SubmoduleRecursive,
+			remote:      req.Params.SubmoduleRemote,
+			failOnError: req.Params.SubmoduleFailOnError,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("resource/in: submodules update: %w", err)
+		}
 	}
 
-	branch, err := cmd.gitBranchOfCommit(commit)
+	pr, err := cmd.getPullRequest(client, req.Version.ID)
 	if err != nil {
-		cmd.Logger.Errorf("resource/in: gitBranchOfCommit: %w", err)
+		return nil, err
+	}
+
+	var sourceBranch, destBranch *string
+	var prMetadata *pullRequestMetadata
+
+	if pr != nil {
+		sourceBranch, destBranch = &pr.Source.Branch.Name, &pr.Dest.Branch.Name
+
+		prMetadata = &pullRequestMetadata{
+			PullRequest: pr,
+			URL:         client.PullrequestURL(req.Version.ID),
+			HeadSHA:     commit.Id().String(),
+			BaseSHA:     cmd.gitFullHash(commit.Owner(), pr.Dest.Commit.Hash),
+		}
+
+		err = cmd.writePullRequestFiles(filepath.Join(destination, req.Params.MetadataDir), req.Params, prMetadata, commit)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// branches guessed from history are not reliable enough for params depending on them
+		if req.Params.ChangedFiles || req.Params.Diff || req.Params.LocalBranch == models.SourceLocalBranch {
+			return nil, fmt.Errorf("resource/in: PR %s not found, its branches required for changed files, diff and source local branch", req.Version.ID)
+		}
+
+		cmd.Logger.Debugf("resource/in: falling back to search of branch in git history")
+
+		sourceBranch, err = cmd.gitBranchOfCommit(commit)
+		if err != nil {
+			cmd.Logger.Errorf("resource/in: gitBranchOfCommit: %s", err)
+		}
+	}
+
+	localBranch, err := localBranchName(req.Params.LocalBranch, req.Version.ID, sourceBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(localBranch) > 0 {
+		if destBranch != nil && *destBranch != localBranch {
+			err = cmd.gitTrackRemoteBranch(commit.Owner(), *destBranch)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = cmd.gitCheckoutLocalBranch(commit, localBranch, sourceBranch)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	cmd.Logger.Debugf("resource/in: version write to %s", concourse.VersionStorageFilename)
@@ -84,87 +175,155 @@ func (cmd *InCommand) Run(destination string, req models.InRequest) (*models.InR
 		},
 	}
 
-	if branch != nil {
+	if prMetadata != nil {
+		response.Metadata = append(response.Metadata, prMetadata.Metadata()...)
+	} else if sourceBranch != nil {
 		response.Metadata = append(response.Metadata, models.MetadataField{
 			Name:  models.BranchMetadataName,
-			Value: *branch,
+			Value: *sourceBranch,
 		})
 	}
 
 	return &response, nil
 }
 
-func (cmd *InCommand) gitCheckoutRef(user, pass string, url, ref string, destination string) (*git.Commit, error) {
-	creds, err := git.NewCredentialUserpassPlaintext(user, pass)
+// runWithoutClone writes version and PR metadata files without cloning the repo.
+// Source is downloaded as an archive unless download is skipped
+func (cmd *InCommand) runWithoutClone(destination string, req models.InRequest, client *bitbucket.Client) (*models.InResponse, error) {
+	cmd.Logger.Debugf("resource/in: no clone, creating destination directory at %s", destination)
+
+	err := os.MkdirAll(destination, 0755)
 	if err != nil {
-		return nil, fmt.Errorf("resource/in: git creds: %w", err)
+		return nil, fmt.Errorf("resource/in: creating destination: %w", err)
 	}
 
-	cmd.Logger.Debugf("resource/in: Clone from repo '%s'", url)
+	if !req.Params.SkipDownload {
+		err = cmd.downloadArchive(client, req.Version.Ref, destination)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	opts := git.CloneOptions{
-		FetchOptions: &git.FetchOptions{
-			RemoteCallbacks: git.RemoteCallbacks{
-				CredentialsCallback: func(url, username_from_url string, allowed_types git.CredentialType) (*git.Credential, error) {
-					return creds, nil
-				},
-				CertificateCheckCallback: func(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
-					return git.ErrorCodeOK
-				},
-			},
+	response := models.InResponse{
+		Version: req.Version,
+		Metadata: models.Metadata{
+			{Name: models.CommitMetadataName, Value: req.Version.Ref},
+			{Name: models.PullrequestURLMetadataName, Value: client.PullrequestURL(req.Version.ID)},
 		},
 	}
 
-	repo, err := git.Clone(url, destination, &opts)
+	pr, err := cmd.getPullRequest(client, req.Version.ID)
 	if err != nil {
-		return nil, fmt.Errorf("resource/in: Cloning: %w", err)
+		return nil, err
 	}
 
-	commit, err := cmd.gitCheckoutDetachedHead(repo, ref)
+	if pr != nil {
+		// PR entity carries abbreviated hash, while metadata files point full one
+		base, err := client.GetCommit(pr.Dest.Commit.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("resource/in: get destination commit: %w", err)
+		}
+
+		metadata := pullRequestMetadata{
+			PullRequest: pr,
+			URL:         client.PullrequestURL(req.Version.ID),
+			HeadSHA:     req.Version.Ref,
+			BaseSHA:     base.Hash,
+		}
+
+		metadataDir := filepath.Join(destination, req.Params.MetadataDir)
+
+		cmd.Logger.Debugf("resource/in: metadata write to %s", metadataDir)
+
+		err = metadata.Write(metadataDir)
+		if err != nil {
+			return nil, fmt.Errorf("resource/in: metadata write: %w", err)
+		}
+
+		response.Metadata = append(response.Metadata, metadata.Metadata()...)
+	}
+
+	cmd.Logger.Debugf("resource/in: version write to %s", concourse.VersionStorageFilename)
+
+	err = concourse.NewStorage(destination, string(concourse.VersionStorageFilename)).Write(&req.Version)
 	if err != nil {
-		return nil, fmt.Errorf("resource/in: Detach head at %s: %w", ref, err)
+		return nil, fmt.Errorf("resource/in: version write: %w", err)
 	}
 
-	return commit, nil
+	return &response, nil
 }
 
-func (cmd InCommand) gitUpdateSubmodules(user, pass string, repo *git.Repository) {
-	opts := &git.SubmoduleUpdateOptions{
-		FetchOptions: &git.FetchOptions{
-			RemoteCallbacks: git.RemoteCallbacks{
-				CredentialsCallback: func(url, username_from_url string, allowed_types git.CredentialType) (*git.Credential, error) {
-					return git.NewCredentialUserpassPlaintext(user, pass)
-				},
-				CertificateCheckCallback: func(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
-					return git.ErrorCodeOK
-				},
-			},
-		},
+// getPullRequest of the version. Nil is returned without error when PR is gone, as its commit still can be fetched.
+// Any other failure is returned, not to succeed without metadata files
+func (cmd *InCommand) getPullRequest(client *bitbucket.Client, id string) (*bitbucket.PullRequestEntity, error) {
+	cmd.Logger.Debugf("resource/in: fetching pull request %s", id)
+
+	pr, err := client.GetPullRequest(id)
+	if bitbucket.IsNotFound(err) {
+		cmd.Logger.Errorf("resource/in: pull request %s not found, metadata files are not written", id)
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: get pull request: %w", err)
 	}
 
-	repo.Submodules.Foreach(func(sub *git.Submodule, name string) int {
-		cmd.Logger.Debugf("resource/in: Submodule '%s' update to commit %s", name, sub.HeadId().String())
+	return pr, nil
+}
 
-		err := sub.Update(true, opts)
+// writePullRequestFiles into metadata dir, including PR changes if requested
+func (cmd *InCommand) writePullRequestFiles(dir string, params models.InParams, metadata *pullRequestMetadata, commit *git.Commit) error {
+	cmd.Logger.Debugf("resource/in: metadata write to %s", dir)
 
-		if err != nil {
-			cmd.Logger.Errorf("resource/in: Submodule '%s' update: %w", name, err)
-		}
+	err := metadata.Write(dir)
+	if err != nil {
+		return fmt.Errorf("resource/in: metadata write: %w", err)
+	}
 
-		repo, err := sub.Open()
+	if !params.ChangedFiles && !params.Diff {
+		return nil
+	}
 
+	diff, err := cmd.gitPullRequestDiff(commit, metadata.PullRequest.Dest.Branch.Name)
+	if err != nil {
+		return fmt.Errorf("resource/in: pull request diff: %w", err)
+	}
+
+	if params.ChangedFiles {
+		err = writeChangedFiles(diff, dir)
 		if err != nil {
-			cmd.Logger.Errorf("resource/in: Submodule '%s' open: %w", name, err)
+			return err
 		}
+	}
 
-		_, err = cmd.gitCheckoutDetachedHead(repo, sub.HeadId().String())
-
+	if params.Diff {
+		err = writeDiff(diff, dir)
 		if err != nil {
-			cmd.Logger.Errorf("resource/in: Submodule '%s' detach head: %w", name, err)
+			return err
 		}
+	}
 
-		return 0
-	})
+	return nil
+}
+
+func (cmd *InCommand) gitCheckoutRef(fetchOpts *git.FetchOptions, url, ref string, destination string) (*git.Commit, error) {
+	cmd.Logger.Debugf("resource/in: Clone from repo '%s'", url)
+
+	opts := git.CloneOptions{
+		FetchOptions: fetchOpts,
+	}
+
+	repo, err := git.Clone(url, destination, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: Cloning: %w", err)
+	}
+
+	commit, err := cmd.gitCheckoutDetachedHead(repo, ref)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: Detach head at %s: %w", ref, err)
+	}
+
+	return commit, nil
 }
 
 func (cmd InCommand) gitCheckoutDetachedHead(repo *git.Repository, ref string) (*git.Commit, error) {
@@ -203,8 +362,43 @@ func (cmd InCommand) gitCheckoutDetachedHead(repo *git.Repository, ref string) (
 	return commit, nil
 }
 
+// gitApplyConfig writes entries into local config of the repo
+func (cmd InCommand) gitApplyConfig(repo *git.Repository, entries map[string]string) error {
+	config, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("resource/in: repo config: %w", err)
+	}
+
+	local, err := config.OpenLevel(config, git.ConfigLevelLocal)
+	if err != nil {
+		return fmt.Errorf("resource/in: repo local config: %w", err)
+	}
+
+	for name, value := range entries {
+		cmd.Logger.Debugf("resource/in: git config %s", name)
+
+		err = local.SetString(name, value)
+		if err != nil {
+			return fmt.Errorf("resource/in: git config %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// gitFullHash expands abbreviated hash returned by BitBucket API. Passed hash is returned when not found in repo
+func (cmd InCommand) gitFullHash(repo *git.Repository, hash string) string {
+	obj, err := repo.RevparseSingle(hash)
+	if err != nil {
+		cmd.Logger.Errorf("resource/in: expand hash %s: %s", hash, err)
+		return hash
+	}
+
+	return obj.Id().String()
+}
+
 // gitBranchOfCommit iterates over commits of every remote branch and compares its refs
-// First ref matches yields name of the branch
+// First ref matches yields name of the branch. Used only when PR could not be fetched from API
 func (cmd InCommand) gitBranchOfCommit(commit *git.Commit) (*string, error) {
 	repo := commit.Owner()
 	if repo == nil {
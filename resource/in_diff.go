@@ -0,0 +1,140 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	git "github.com/libgit2/git2go/v31"
+)
+
+const (
+	changedFilesPullRequestFilename pullRequestFilename = "changed_files"
+	diffPullRequestFilename         pullRequestFilename = "pr.diff"
+)
+
+// deltaStatus maps libgit2 delta to the letter used by `git diff --name-status`
+func deltaStatus(delta git.Delta) string {
+	switch delta {
+	case git.DeltaAdded:
+		return "A"
+	case git.DeltaDeleted:
+		return "D"
+	case git.DeltaModified:
+		return "M"
+	case git.DeltaRenamed:
+		return "R"
+	case git.DeltaCopied:
+		return "C"
+	case git.DeltaTypeChange:
+		return "T"
+	}
+
+	return ""
+}
+
+// gitPullRequestDiff computes diff between merge-base of PR head with destination branch and PR head itself,
+// which is what BitBucket presents as PR changes
+func (cmd InCommand) gitPullRequestDiff(head *git.Commit, destBranch string) (*git.Diff, error) {
+	repo := head.Owner()
+
+	destObj, err := repo.RevparseSingle("origin/" + destBranch)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: revparse destination branch %s: %w", destBranch, err)
+	}
+
+	baseID, err := repo.MergeBase(head.Id(), destObj.Id())
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: merge base: %w", err)
+	}
+
+	cmd.Logger.Debugf("resource/in: diff %s..%s", baseID.String(), head.Id().String())
+
+	base, err := repo.LookupCommit(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: lookup merge base: %w", err)
+	}
+
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: merge base tree: %w", err)
+	}
+
+	headTree, err := head.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: head tree: %w", err)
+	}
+
+	diff, err := repo.DiffTreeToTree(baseTree, headTree, nil)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: diff trees: %w", err)
+	}
+
+	findOpts, err := git.DefaultDiffFindOptions()
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: diff find options: %w", err)
+	}
+
+	findOpts.Flags |= git.DiffFindRenames
+
+	err = diff.FindSimilar(&findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("resource/in: diff find renames: %w", err)
+	}
+
+	return diff, nil
+}
+
+// writeChangedFiles lists changed paths one per line, prefixed with status letter as `git diff --name-status` does
+func writeChangedFiles(diff *git.Diff, dir string) error {
+	count, err := diff.NumDeltas()
+	if err != nil {
+		return fmt.Errorf("resource/in: diff deltas: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+
+	for i := 0; i < count; i++ {
+		delta, err := diff.Delta(i)
+		if err != nil {
+			return fmt.Errorf("resource/in: diff delta %d: %w", i, err)
+		}
+
+		status := deltaStatus(delta.Status)
+		if len(status) == 0 {
+			continue
+		}
+
+		switch delta.Status {
+		case git.DeltaRenamed, git.DeltaCopied:
+			fmt.Fprintf(buf, "%s\t%s\t%s\n", status, delta.OldFile.Path, delta.NewFile.Path)
+		case git.DeltaDeleted:
+			fmt.Fprintf(buf, "%s\t%s\n", status, delta.OldFile.Path)
+		default:
+			fmt.Fprintf(buf, "%s\t%s\n", status, delta.NewFile.Path)
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, string(changedFilesPullRequestFilename)), buf.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("resource/in: write %s: %w", changedFilesPullRequestFilename, err)
+	}
+
+	return nil
+}
+
+// writeDiff stores diff in unified patch format
+func writeDiff(diff *git.Diff, dir string) error {
+	patch, err := diff.ToBuf(git.DiffFormatPatch)
+	if err != nil {
+		return fmt.Errorf("resource/in: diff to patch: %w", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, string(diffPullRequestFilename)), patch, 0644)
+	if err != nil {
+		return fmt.Errorf("resource/in: write %s: %w", diffPullRequestFilename, err)
+	}
+
+	return nil
+}
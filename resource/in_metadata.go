@@ -0,0 +1,97 @@
+package resource
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/concourse"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// pullRequestMetadata describes PR being built, written as files for the tasks
+type pullRequestMetadata struct {
+	PullRequest *bitbucket.PullRequestEntity
+	URL         string
+	HeadSHA     string
+	BaseSHA     string
+}
+
+// pullRequestFilename enumerates names of the files in metadata directory
+type pullRequestFilename string
+
+const (
+	idPullRequestFilename                pullRequestFilename = "id"
+	titlePullRequestFilename             pullRequestFilename = "title"
+	descriptionPullRequestFilename       pullRequestFilename = "description"
+	sourceBranchPullRequestFilename      pullRequestFilename = "source_branch"
+	destinationBranchPullRequestFilename pullRequestFilename = "destination_branch"
+	authorPullRequestFilename            pullRequestFilename = "author"
+	urlPullRequestFilename               pullRequestFilename = "url"
+	headSHAPullRequestFilename           pullRequestFilename = "head_sha"
+	baseSHAPullRequestFilename           pullRequestFilename = "base_sha"
+	entityPullRequestFilename            pullRequestFilename = "pr.json"
+)
+
+// Metadata of PR presented in Concourse UI
+func (m pullRequestMetadata) Metadata() models.Metadata {
+	pr := m.PullRequest
+
+	reviewers := make([]string, 0, len(pr.Reviewers))
+	for _, r := range pr.Reviewers {
+		reviewers = append(reviewers, r.Name)
+	}
+
+	return models.Metadata{
+		{Name: models.TitleMetadataName, Value: pr.Title},
+		{Name: models.StateMetadataName, Value: string(pr.State)},
+		{Name: models.BranchMetadataName, Value: pr.Source.Branch.Name},
+		{Name: models.SourceBranchMetadataName, Value: pr.Source.Branch.Name},
+		{Name: models.DestinationBranchMetadataName, Value: pr.Dest.Branch.Name},
+		{Name: models.DestinationCommitMetadataName, Value: m.BaseSHA},
+		{Name: models.PullrequestAuthorMetadataName, Value: pr.Author.Name},
+		{Name: models.ReviewersMetadataName, Value: strings.Join(reviewers, ", ")},
+		{Name: models.ApprovalsMetadataName, Value: strconv.Itoa(pr.Approvals())},
+		{Name: models.CreatedMetadataName, Value: pr.CreatedOn},
+		{Name: models.UpdatedMetadataName, Value: pr.UpdatedOn},
+		{Name: models.CommentsMetadataName, Value: strconv.Itoa(pr.CommentCount)},
+	}
+}
+
+// Write one file per field and full PR entity as JSON into dir
+func (m pullRequestMetadata) Write(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("resource/in: metadata dir: %w", err)
+	}
+
+	files := map[pullRequestFilename]string{
+		idPullRequestFilename:                strconv.Itoa(m.PullRequest.ID),
+		titlePullRequestFilename:             m.PullRequest.Title,
+		descriptionPullRequestFilename:       m.PullRequest.Description,
+		sourceBranchPullRequestFilename:      m.PullRequest.Source.Branch.Name,
+		destinationBranchPullRequestFilename: m.PullRequest.Dest.Branch.Name,
+		authorPullRequestFilename:            m.PullRequest.Author.Name,
+		urlPullRequestFilename:               m.URL,
+		headSHAPullRequestFilename:           m.HeadSHA,
+		baseSHAPullRequestFilename:           m.BaseSHA,
+	}
+
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, string(name)), []byte(content), 0644)
+		if err != nil {
+			return fmt.Errorf("resource/in: metadata file %s: %w", name, err)
+		}
+	}
+
+	err = concourse.NewStorage(dir, string(entityPullRequestFilename)).Write(m.PullRequest)
+	if err != nil {
+		return fmt.Errorf("resource/in: metadata file %s: %w", entityPullRequestFilename, err)
+	}
+
+	return nil
+}
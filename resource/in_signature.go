@@ -0,0 +1,161 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	pgpPublicKeyPrefix string = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpSignaturePrefix string = "-----BEGIN PGP SIGNATURE-----"
+
+	sshSignaturePEMType string = "SSH SIGNATURE"
+	sshSignatureMagic   string = "SSHSIG"
+	sshGitNamespace     string = "git"
+)
+
+// verifyCommitSignature checks commit is signed by one of trusted keys, either OpenPGP or SSH
+func verifyCommitSignature(commit *git.Commit, trustedKeys []string) error {
+	signature, signed, err := commit.ExtractSignature()
+	if err != nil {
+		return fmt.Errorf("resource/in: commit %s is not signed: %w", commit.Id().String(), err)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(signature), pgpSignaturePrefix) {
+		return verifyPGPSignature(signature, signed, trustedKeys)
+	}
+
+	return verifySSHSignature(signature, signed, trustedKeys)
+}
+
+func verifyPGPSignature(signature, signed string, trustedKeys []string) error {
+	keyring := openpgp.EntityList{}
+
+	for _, key := range trustedKeys {
+		if !strings.HasPrefix(strings.TrimSpace(key), pgpPublicKeyPrefix) {
+			continue
+		}
+
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("resource/in: read trusted pgp key: %w", err)
+		}
+
+		keyring = append(keyring, entities...)
+	}
+
+	_, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(signed), strings.NewReader(signature))
+	if err != nil {
+		return fmt.Errorf("resource/in: pgp signature not trusted: %w", err)
+	}
+
+	return nil
+}
+
+// sshSignature is a blob of `ssh-keygen -Y sign` output, see PROTOCOL.sshsig of OpenSSH
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is a structure which is actually signed by SSH key
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func verifySSHSignature(signature, signed string, trustedKeys []string) error {
+	block, _ := pem.Decode([]byte(signature))
+	if block == nil || block.Type != sshSignaturePEMType {
+		return errors.New("resource/in: signature is neither pgp nor ssh")
+	}
+
+	if !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		return errors.New("resource/in: ssh signature magic mismatch")
+	}
+
+	var sig sshSignature
+
+	err := ssh.Unmarshal(block.Bytes[len(sshSignatureMagic):], &sig)
+	if err != nil {
+		return fmt.Errorf("resource/in: ssh signature decode: %w", err)
+	}
+
+	if sig.Namespace != sshGitNamespace {
+		return fmt.Errorf("resource/in: ssh signature namespace %s is not git", sig.Namespace)
+	}
+
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return fmt.Errorf("resource/in: ssh signature public key: %w", err)
+	}
+
+	if !sshKeyTrusted(pub, trustedKeys) {
+		return fmt.Errorf("resource/in: ssh key %s not trusted", ssh.FingerprintSHA256(pub))
+	}
+
+	var h hash.Hash
+
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("resource/in: ssh signature hash algorithm %s not supported", sig.HashAlgorithm)
+	}
+
+	h.Write([]byte(signed))
+
+	data := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+
+	var s ssh.Signature
+
+	err = ssh.Unmarshal(sig.Signature, &s)
+	if err != nil {
+		return fmt.Errorf("resource/in: ssh signature blob decode: %w", err)
+	}
+
+	err = pub.Verify(data, &s)
+	if err != nil {
+		return fmt.Errorf("resource/in: ssh signature not valid: %w", err)
+	}
+
+	return nil
+}
+
+func sshKeyTrusted(pub ssh.PublicKey, trustedKeys []string) bool {
+	for _, key := range trustedKeys {
+		trusted, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			continue
+		}
+
+		if bytes.Equal(trusted.Marshal(), pub.Marshal()) {
+			return true
+		}
+	}
+
+	return false
+}
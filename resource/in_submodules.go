@@ -0,0 +1,124 @@
+package resource
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// submoduleOptions controls which submodules are updated and how
+type submoduleOptions struct {
+	submodules  models.Submodules
+	recursive   bool
+	remote      bool
+	failOnError bool
+}
+
+// gitUpdateSubmodules of repo selected by opts. Errors are only logged unless failOnError is set
+func (cmd InCommand) gitUpdateSubmodules(trans *transport, parentURL string, repo *git.Repository, opts submoduleOptions) error {
+	var updateErr error
+
+	err := repo.Submodules.Foreach(func(sub *git.Submodule, name string) int {
+		if !opts.submodules.Includes(sub.Path()) {
+			cmd.Logger.Debugf("resource/in: Submodule '%s' skipped", name)
+			return 0
+		}
+
+		err := cmd.gitUpdateSubmodule(trans, parentURL, repo, sub, name, opts)
+		if err == nil {
+			return 0
+		}
+
+		if opts.failOnError {
+			updateErr = err
+			return -1
+		}
+
+		cmd.Logger.Errorf("%s", err)
+
+		return 0
+	})
+
+	if updateErr != nil {
+		return updateErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("resource/in: submodules foreach: %w", err)
+	}
+
+	return nil
+}
+
+func (cmd InCommand) gitUpdateSubmodule(trans *transport, parentURL string, parent *git.Repository, sub *git.Submodule, name string, opts submoduleOptions) error {
+	subURL := submoduleURL(parentURL, sub.Url())
+
+	cmd.Logger.Debugf("resource/in: Submodule '%s' update to commit %s", name, sub.HeadId().String())
+
+	err := sub.Update(true, &git.SubmoduleUpdateOptions{
+		FetchOptions: trans.SubmoduleFetchOptions(subURL),
+	})
+	if err != nil {
+		return fmt.Errorf("resource/in: Submodule '%s' update: %w", name, err)
+	}
+
+	repo, err := sub.Open()
+	if err != nil {
+		return fmt.Errorf("resource/in: Submodule '%s' open: %w", name, err)
+	}
+
+	ref := sub.HeadId().String()
+	if opts.remote {
+		ref = "origin/" + cmd.submoduleBranch(parent, name)
+	}
+
+	_, err = cmd.gitCheckoutDetachedHead(repo, ref)
+	if err != nil {
+		return fmt.Errorf("resource/in: Submodule '%s' detach head: %w", name, err)
+	}
+
+	if !opts.recursive {
+		return nil
+	}
+
+	nested := opts
+	nested.submodules = models.Submodules{All: true}
+
+	return cmd.gitUpdateSubmodules(trans, subURL, repo, nested)
+}
+
+// submoduleBranch configured in .gitmodules, remote HEAD otherwise
+func (cmd InCommand) submoduleBranch(parent *git.Repository, name string) string {
+	config, err := git.OpenOndisk(filepath.Join(parent.Workdir(), ".gitmodules"))
+	if err != nil {
+		return "HEAD"
+	}
+
+	branch, err := config.LookupString("submodule." + name + ".branch")
+	if err != nil || len(branch) == 0 || branch == "." {
+		return "HEAD"
+	}
+
+	return branch
+}
+
+// submoduleURL resolves submodule URL relative to the parent repo, as `../other.git` is common for BitBucket
+func submoduleURL(parentURL, subURL string) string {
+	base, err := url.Parse(parentURL)
+	if err != nil {
+		return subURL
+	}
+
+	ref, err := url.Parse(subURL)
+	if err != nil {
+		return subURL
+	}
+
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/"
+
+	return base.ResolveReference(ref).String()
+}
@@ -3,6 +3,9 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
 )
 
 /*
@@ -34,9 +37,9 @@ func (s Version) Validate() error {
 
 // InRequest input for In stage
 type InRequest struct {
-	Source  Source  `json:"source"`
-	Version Version `json:"version"`
-	Params  Params  `json:"params"`
+	Source  Source   `json:"source"`
+	Version Version  `json:"version"`
+	Params  InParams `json:"params"`
 }
 
 // InResponse output for In stage
@@ -72,12 +75,31 @@ type CheckResponse []Version
 
 // Source object with configuration of whole resource instance
 type Source struct {
-	Workspace         string `json:"workspace"`
-	Slug              string `json:"slug"`
-	Username          string `json:"username"`
-	Password          string `json:"password"`
-	Debug             bool   `json:"debug"`
-	RecurseSubmodules bool   `json:"recurse_submodules"`
+	Workspace           string `json:"workspace"`
+	Slug                string `json:"slug"`
+	Username            string `json:"username"`
+	Password            string `json:"password"`
+	AccessToken         string `json:"access_token"`
+	OAuthKey            string `json:"oauth_key"`
+	OAuthSecret         string `json:"oauth_secret"`
+	Debug               bool   `json:"debug"`
+	RecurseSubmodules   bool   `json:"recurse_submodules"`
+	CACerts             string `json:"ca_certs"`
+	SkipSSLVerification bool   `json:"skip_ssl_verification"`
+	Proxy               string `json:"proxy"`
+	NoProxy             string `json:"no_proxy"`
+
+	SubmoduleCredentials []SubmoduleCredentials `json:"submodule_credentials"`
+
+	// GitConfig entries written into config of cloned repo, ie. user.name
+	GitConfig map[string]string `json:"git_config"`
+}
+
+// SubmoduleCredentials used for submodules hosted on the given host
+type SubmoduleCredentials struct {
+	Host     string `json:"host"`
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 // Validate Source object against required fields
@@ -86,7 +108,19 @@ func (s Source) Validate() error {
 		return errors.New("resource/model: workspace name and/or repo slug is empty")
 	}
 
-	if len(s.Username) == 0 || len(s.Username) == 0 {
+	if len(s.AccessToken) > 0 {
+		return nil
+	}
+
+	if len(s.OAuthKey) > 0 || len(s.OAuthSecret) > 0 {
+		if len(s.OAuthKey) == 0 || len(s.OAuthSecret) == 0 {
+			return errors.New("resource/model: oauth key and/or secret is empty")
+		}
+
+		return nil
+	}
+
+	if len(s.Username) == 0 || len(s.Password) == 0 {
 		return errors.New("resource/model: basic auth is empty")
 	}
 
@@ -97,60 +131,164 @@ func (s Source) Validate() error {
 	Params object schema
 */
 
-// ParamsOutAction enumerates types of the action performed during Out stage of resource
-type ParamsOutAction string
+// LocalBranch enumerates naming of local branch created at PR head during In stage
+type LocalBranch string
 
 const (
-	// CommitBuildStatusSetParamsOutAction updates/creates build status for HEAD of current resource version
-	CommitBuildStatusSetParamsOutAction ParamsOutAction = "set:commit.build.status"
+	// NoneLocalBranch leaves repo with detached HEAD
+	NoneLocalBranch LocalBranch = "none"
+
+	// SourceLocalBranch names local branch after PR source branch
+	SourceLocalBranch LocalBranch = "source"
+
+	// PullRequestLocalBranch names local branch after PR identifier, ie. pr-42
+	PullRequestLocalBranch LocalBranch = "pr-<id>"
 )
 
-// Params object containing configuration of single resource invocation
-type Params struct {
-	RepoPath    string          `json:"repo_path"`
-	Action      ParamsOutAction `json:"action"`
-	Key         string          `json:"key"`
-	Status      string          `json:"status"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	URL         string          `json:"url"`
+// Submodules selects submodules updated during In stage: all, none or listed paths
+type Submodules struct {
+	All   bool
+	Paths []string
 }
 
-func (p *Params) UnmarshalJSON(data []byte) error {
-	type paramsDefaults Params
-	defaults := &paramsDefaults{
-		Action:      CommitBuildStatusSetParamsOutAction,
-		Key:         "BUILD",
-		Name:        "$BUILD_JOB_NAME #$BUILD_ID",
-		Description: "Concourse Build CI",
+func (s *Submodules) UnmarshalJSON(data []byte) error {
+	var option string
+
+	if err := json.Unmarshal(data, &option); err == nil {
+		switch option {
+		case "all":
+			*s = Submodules{All: true}
+		case "none":
+			*s = Submodules{}
+		default:
+			return fmt.Errorf("resource/model: submodules %s is not one of all, none or list of paths", option)
+		}
+
+		return nil
 	}
 
-	err := json.Unmarshal(data, defaults)
+	var paths []string
+
+	err := json.Unmarshal(data, &paths)
 	if err != nil {
-		return err
+		return fmt.Errorf("resource/model: submodules: %w", err)
 	}
 
-	*p = Params(*defaults)
+	*s = Submodules{Paths: paths}
 
 	return nil
 }
 
-// Validate Params object against required fields
-func (p Params) Validate() error {
-	if len(p.Action) == 0 && len(p.RepoPath) != 0 {
-		return errors.New("resource/model: action is empty or invalid")
+// Includes submodule at path
+func (s Submodules) Includes(path string) bool {
+	if s.All {
+		return true
+	}
+
+	for _, p := range s.Paths {
+		if strings.Trim(p, "/") == strings.Trim(path, "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Empty when no submodule is selected
+func (s Submodules) Empty() bool {
+	return !s.All && len(s.Paths) == 0
+}
+
+// InFormat enumerates ways of fetching PR source during In stage
+type InFormat string
+
+const (
+	// GitInFormat clones the repo with whole history
+	GitInFormat InFormat = "git"
+
+	// ArchiveInFormat downloads and extracts tarball of PR head commit
+	ArchiveInFormat InFormat = "archive"
+)
+
+// InParams object containing configuration of single get step
+type InParams struct {
+	MetadataDir  string      `json:"metadata_dir"`
+	ChangedFiles bool        `json:"changed_files"`
+	Diff         bool        `json:"diff"`
+	LocalBranch  LocalBranch `json:"local_branch"`
+
+	// Submodules overrides source recurse_submodules when set
+	Submodules           *Submodules `json:"submodules"`
+	SubmoduleRecursive   bool        `json:"submodule_recursive"`
+	SubmoduleRemote      bool        `json:"submodule_remote"`
+	SubmoduleFailOnError bool        `json:"submodule_fail_on_error"`
+
+	// SkipDownload writes version and metadata only, without cloning the repo
+	SkipDownload bool     `json:"skip_download"`
+	Format       InFormat `json:"format"`
+
+	// VerifySignatures requires PR head commit to be signed by one of TrustedKeys (armored PGP or SSH public keys)
+	VerifySignatures bool     `json:"verify_signatures"`
+	TrustedKeys      []string `json:"trusted_keys"`
+}
+
+// WithDefaults fills params not set in the request. Concourse omits params object of plain get step,
+// so defaults can't be applied during unmarshalling
+func (p InParams) WithDefaults() InParams {
+	if len(p.LocalBranch) == 0 {
+		p.LocalBranch = NoneLocalBranch
+	}
+
+	if len(p.Format) == 0 {
+		p.Format = GitInFormat
+	}
+
+	if len(p.MetadataDir) == 0 {
+		p.MetadataDir = ".git/resource"
+
+		if !p.clones() {
+			// there is no .git directory without clone
+			p.MetadataDir = ".resource"
+		}
+	}
+
+	return p
+}
+
+// clones the repo, other modes write plain tree or nothing at all
+func (p InParams) clones() bool {
+	return !p.SkipDownload && p.Format != ArchiveInFormat
+}
+
+// Validate InParams object against allowed values
+func (p InParams) Validate() error {
+	switch p.LocalBranch {
+	case NoneLocalBranch, SourceLocalBranch, PullRequestLocalBranch:
+	default:
+		return fmt.Errorf("resource/model: local branch %s is not one of none, source, pr-<id>", p.LocalBranch)
+	}
+
+	switch p.Format {
+	case GitInFormat, ArchiveInFormat:
+	default:
+		return fmt.Errorf("resource/model: format %s is not one of git, archive", p.Format)
+	}
+
+	dir := filepath.Clean(p.MetadataDir)
+	if filepath.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("resource/model: metadata dir %s is not a subdirectory of destination", p.MetadataDir)
 	}
 
-	if len(p.Status) == 0 {
-		return errors.New("resource/model: status is empty")
+	if !p.clones() && (p.ChangedFiles || p.Diff || p.VerifySignatures) {
+		return errors.New("resource/model: changed files, diff and signature verification require repo clone")
 	}
 
-	if len(p.RepoPath) == 0 {
-		return errors.New("resource/model: repo path is empty")
+	if !p.clones() && (p.LocalBranch != NoneLocalBranch || p.Submodules != nil || p.SubmoduleRecursive || p.SubmoduleRemote) {
+		return errors.New("resource/model: local branch and submodules require repo clone")
 	}
 
-	if len(p.URL) == 0 {
-		return errors.New("resource/model: urls is empty")
+	if p.VerifySignatures && len(p.TrustedKeys) == 0 {
+		return errors.New("resource/model: trusted keys are required to verify signatures")
 	}
 
 	return nil
@@ -173,6 +311,12 @@ const (
 	// BranchMetadataName inidicates name of branch which contains current commit
 	BranchMetadataName MetadataName = "branch"
 
+	// SourceBranchMetadataName indicates name of PR source branch
+	SourceBranchMetadataName MetadataName = "source_branch"
+
+	// DestinationBranchMetadataName indicates name of PR destination branch
+	DestinationBranchMetadataName MetadataName = "destination_branch"
+
 	// MessageMetadataName inidicates message of PR-ed head commit
 	MessageMetadataName MetadataName = "message"
 
@@ -184,6 +328,54 @@ const (
 
 	// PullrequestURLMetadataName contains URL to Bitbucket service for a given PR
 	PullrequestURLMetadataName MetadataName = "pullrequest"
+
+	// TitleMetadataName contains title of PR
+	TitleMetadataName MetadataName = "title"
+
+	// StateMetadataName contains state of PR, ie. OPEN
+	StateMetadataName MetadataName = "state"
+
+	// DestinationCommitMetadataName contains hash of destination branch commit PR is based on
+	DestinationCommitMetadataName MetadataName = "destination_commit"
+
+	// PullrequestAuthorMetadataName indicates author of PR, who may differ from author of commit
+	PullrequestAuthorMetadataName MetadataName = "pullrequest_author"
+
+	// ReviewersMetadataName contains comma separated names of PR reviewers
+	ReviewersMetadataName MetadataName = "reviewers"
+
+	// ApprovalsMetadataName contains number of PR approvals
+	ApprovalsMetadataName MetadataName = "approvals"
+
+	// CreatedMetadataName contains timestamp of PR creation
+	CreatedMetadataName MetadataName = "created_on"
+
+	// UpdatedMetadataName contains timestamp of last PR update
+	UpdatedMetadataName MetadataName = "updated_on"
+
+	// CommentsMetadataName contains number of PR comments
+	CommentsMetadataName MetadataName = "comments"
+
+	// CommentMetadataName contains identifier of comment posted by Out stage
+	CommentMetadataName MetadataName = "comment"
+
+	// MergeCommitMetadataName contains hash of commit created by merging PR
+	MergeCommitMetadataName MetadataName = "merge_commit"
+
+	// ReportMetadataName contains identifier of Code Insights report created by Out stage
+	ReportMetadataName MetadataName = "report"
+
+	// AnnotationsMetadataName contains number of annotations uploaded with Code Insights report
+	AnnotationsMetadataName MetadataName = "annotations"
+
+	// StoppedMetadataName contains number of superseded build statuses stopped by Out stage
+	StoppedMetadataName MetadataName = "stopped"
+
+	// TaskMetadataName contains identifier of PR task created by Out stage
+	TaskMetadataName MetadataName = "task"
+
+	// ResolvedMetadataName contains number of PR tasks resolved by Out stage
+	ResolvedMetadataName MetadataName = "resolved"
 )
 
 // MetadataField as single entity of additional info in Concourse
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInParamsSubmodules(t *testing.T) {
+	cases := []struct {
+		json     string
+		path     string
+		expected bool
+	}{
+		{`{"submodules": "all"}`, "libs/a", true},
+		{`{"submodules": "none"}`, "libs/a", false},
+		{`{"submodules": ["libs/a/"]}`, "libs/a", true},
+		{`{"submodules": ["libs/a"]}`, "libs/b", false},
+	}
+
+	for _, c := range cases {
+		var params InParams
+
+		err := json.Unmarshal([]byte(c.json), &params)
+		if err != nil {
+			t.Fatalf("%s: %s", c.json, err)
+		}
+
+		if params.Submodules.Includes(c.path) != c.expected {
+			t.Errorf("%s: expected %s included %t", c.json, c.path, c.expected)
+		}
+	}
+}
+
+func TestInParamsSubmodulesInvalid(t *testing.T) {
+	var params InParams
+
+	err := json.Unmarshal([]byte(`{"submodules": "some"}`), &params)
+	if err == nil {
+		t.Error("expected error for invalid submodules option")
+	}
+}
+
+func TestInParamsDefaults(t *testing.T) {
+	var req InRequest
+
+	// plain get step, Concourse sends no params object
+	err := json.Unmarshal([]byte(`{"source": {"workspace": "w", "slug": "s"}, "version": {"ref": "abc", "id": "1"}}`), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Params.Validate() == nil {
+		t.Error("expected params without defaults to be invalid")
+	}
+
+	params := req.Params.WithDefaults()
+
+	if params.Submodules != nil || params.LocalBranch != NoneLocalBranch || params.Format != GitInFormat || params.MetadataDir != ".git/resource" {
+		t.Errorf("unexpected defaults %+v", params)
+	}
+
+	if params.Validate() != nil {
+		t.Error("expected defaults to be valid")
+	}
+}
+
+func TestInParamsValidate(t *testing.T) {
+	cases := []struct {
+		json  string
+		valid bool
+	}{
+		{`{}`, true},
+		{`{"format": "archive"}`, true},
+		{`{"format": "zip"}`, false},
+		{`{"skip_download": true, "diff": true}`, false},
+		{`{"format": "archive", "local_branch": "source"}`, false},
+		{`{"skip_download": true, "submodules": "all"}`, false},
+		{`{"format": "archive", "submodule_recursive": true}`, false},
+		{`{"local_branch": "source", "submodules": ["libs/a"]}`, true},
+		{`{"verify_signatures": true}`, false},
+		{`{"local_branch": "main"}`, false},
+		{`{"metadata_dir": "meta/pr"}`, true},
+		{`{"metadata_dir": "meta/../pr"}`, true},
+		{`{"metadata_dir": "../../x"}`, false},
+		{`{"metadata_dir": "meta/../../x"}`, false},
+		{`{"metadata_dir": "/tmp/meta"}`, false},
+		{`{"metadata_dir": "."}`, false},
+	}
+
+	for _, c := range cases {
+		var params InParams
+
+		err := json.Unmarshal([]byte(c.json), &params)
+		if err != nil {
+			t.Fatalf("%s: %s", c.json, err)
+		}
+
+		err = params.WithDefaults().Validate()
+		if (err == nil) != c.valid {
+			t.Errorf("%s: expected valid %t, got %v", c.json, c.valid, err)
+		}
+	}
+}
+
+func TestInParamsMetadataDirDefault(t *testing.T) {
+	cases := []struct {
+		params   InParams
+		expected string
+	}{
+		{InParams{}, ".git/resource"},
+		{InParams{Format: ArchiveInFormat}, ".resource"},
+		{InParams{SkipDownload: true}, ".resource"},
+		{InParams{SkipDownload: true, MetadataDir: "meta"}, "meta"},
+	}
+
+	for _, c := range cases {
+		dir := c.params.WithDefaults().MetadataDir
+		if dir != c.expected {
+			t.Errorf("%+v: expected metadata dir %s, got %s", c.params, c.expected, dir)
+		}
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	cases := []struct {
+		json  string
+		valid bool
+	}{
+		{`{"repo_path": "pr", "status": "SUCCESSFUL", "url": "https://ci"}`, true},
+		{`{"repo_path": "pr", "status": "DONE", "url": "https://ci"}`, false},
+		{`{"repo_path": "pr", "status": "SUCCESSFUL"}`, true},
+		{`{"repo_path": "pr", "status_file": "result/status", "url_file": "result/url"}`, true},
+		{`{"repo_path": "pr", "url": "https://ci"}`, false},
+		{`{"repo_path": "pr", "statuses": [{"key": "ios", "status": "SUCCESSFUL"}, {"key": "android", "status": "FAILED"}]}`, true},
+		{`{"repo_path": "pr", "statuses": [{"key": "ios", "status": "SUCCESSFUL"}, {"key": "ios", "status": "FAILED"}]}`, false},
+		{`{"repo_path": "pr", "statuses": [{"status": "SUCCESSFUL"}]}`, false},
+		{`{"repo_path": "pr", "status": "SUCCESSFUL", "status_target": "all_pr_commits"}`, true},
+		{`{"repo_path": "pr", "status": "SUCCESSFUL", "status_target": "branch"}`, false},
+		{`{"repo_path": "pr", "action": "add:comment", "comment": "LGTM"}`, true},
+		{`{"repo_path": "pr", "action": "add:comment"}`, false},
+		{`{"repo_path": "pr", "action": "add:comment", "comment_file": "report/summary.md", "comment_key": "coverage"}`, true},
+		{`{"repo_path": "pr", "action": "add:comment", "comment": "LGTM", "comment_file": "report/summary.md"}`, false},
+		{`{"repo_path": "pr", "action": "approve"}`, true},
+		{`{"repo_path": "pr", "action": "stop:superseded", "key": "ios"}`, true},
+		{`{"repo_path": "pr", "action": "merge", "merge_strategy": "squash", "close_source_branch": true}`, true},
+		{`{"repo_path": "pr", "action": "merge", "merge_strategy": "rebase"}`, false},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "coverage", "report_type": "COVERAGE", "report_title": "Coverage", "report_data": [{"title": "Lines", "type": "PERCENTAGE", "value": 85.5}]}`, true},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "lint", "report_file": "lint/report.json"}`, true},
+		{`{"repo_path": "pr", "action": "create:report", "report_title": "Coverage"}`, false},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "coverage", "report_title": "Coverage"}`, false},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "coverage", "report_title": "Coverage", "report_type": "LINT"}`, false},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "tests", "report_type": "TEST", "report_title": "Tests", "report_result": "OK"}`, false},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "lint", "report_type": "BUG", "report_title": "Lint", "report_annotations": [{"path": "lint/*.xml", "format": "checkstyle"}]}`, true},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "lint", "report_type": "BUG", "report_title": "Lint", "report_annotations": [{"path": "lint.txt", "format": "text"}]}`, false},
+		{`{"repo_path": "pr", "action": "create:report", "report_id": "lint", "report_type": "BUG", "report_title": "Lint", "report_max_annotations": 5000}`, false},
+		{`{"repo_path": "pr", "action": "add:reviewers", "reviewers": ["{a1b2}", "557058:c3d4"]}`, true},
+		{`{"repo_path": "pr", "action": "add:reviewers", "reviewers": ["{a1b2}", "group:qa"]}`, false},
+		{`{"repo_path": "pr", "action": "remove:reviewers"}`, false},
+		{`{"repo_path": "pr", "action": "create:task", "task": "Fix lint errors", "comment_key": "lint"}`, true},
+		{`{"repo_path": "pr", "action": "resolve:task", "comment_key": "lint"}`, true},
+		{`{"repo_path": "pr", "action": "resolve:task"}`, false},
+		{`{"repo_path": "pr", "action": "unknown"}`, false},
+		{`{"action": "approve"}`, false},
+		{`{"action": "create:pullrequest", "pullrequest_title": "Back-merge", "source_branch": "release/1.0", "destination_branch": "develop"}`, true},
+		{`{"action": "create:pullrequest", "pullrequest_title": "Back-merge", "source_branch": "release/1.0"}`, false},
+	}
+
+	for _, c := range cases {
+		var params Params
+
+		err := json.Unmarshal([]byte(c.json), &params)
+		if err != nil {
+			t.Fatalf("%s: %s", c.json, err)
+		}
+
+		err = params.Validate()
+		if (err == nil) != c.valid {
+			t.Errorf("%s: expected valid %t, got %v", c.json, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+)
+
+// ParamsOutAction enumerates types of the action performed during Out stage of resource
+type ParamsOutAction string
+
+const (
+	// CommitBuildStatusSetParamsOutAction updates/creates build status for HEAD of current resource version
+	CommitBuildStatusSetParamsOutAction ParamsOutAction = "set:commit.build.status"
+
+	// CommentAddParamsOutAction posts comment to PR of current resource version
+	CommentAddParamsOutAction ParamsOutAction = "add:comment"
+
+	// ApproveParamsOutAction approves PR of current resource version
+	ApproveParamsOutAction ParamsOutAction = "approve"
+
+	// UnapproveParamsOutAction withdraws approval of PR of current resource version
+	UnapproveParamsOutAction ParamsOutAction = "unapprove"
+
+	// RequestChangesParamsOutAction requests changes in PR of current resource version
+	RequestChangesParamsOutAction ParamsOutAction = "request:changes"
+
+	// MergeParamsOutAction merges PR of current resource version
+	MergeParamsOutAction ParamsOutAction = "merge"
+
+	// DeclineParamsOutAction declines PR of current resource version
+	DeclineParamsOutAction ParamsOutAction = "decline"
+
+	// SupersededStopParamsOutAction stops in progress build statuses of PR commits other than its head
+	SupersededStopParamsOutAction ParamsOutAction = "stop:superseded"
+
+	// PullRequestCreateParamsOutAction creates PR, or finds open one of the same branches, and emits it as a version
+	PullRequestCreateParamsOutAction ParamsOutAction = "create:pullrequest"
+
+	// ReviewersAddParamsOutAction adds reviewers to PR of current resource version
+	ReviewersAddParamsOutAction ParamsOutAction = "add:reviewers"
+
+	// ReviewersRemoveParamsOutAction removes reviewers from PR of current resource version
+	ReviewersRemoveParamsOutAction ParamsOutAction = "remove:reviewers"
+
+	// TaskCreateParamsOutAction creates task in PR of current resource version
+	TaskCreateParamsOutAction ParamsOutAction = "create:task"
+
+	// TaskResolveParamsOutAction resolves tasks in PR of current resource version
+	TaskResolveParamsOutAction ParamsOutAction = "resolve:task"
+
+	// ReportCreateParamsOutAction creates Code Insights report for HEAD of current resource version
+	ReportCreateParamsOutAction ParamsOutAction = "create:report"
+)
+
+// StatusTarget enumerates commits which build status is set on
+type StatusTarget string
+
+const (
+	// HeadStatusTarget is HEAD of the repo checked out by get step, or commit of the version if repo was not downloaded
+	HeadStatusTarget StatusTarget = "head"
+
+	// VersionStatusTarget is PR source commit stored in the version
+	VersionStatusTarget StatusTarget = "version"
+
+	// MergeParentsStatusTarget are parents of HEAD, if it is a merge commit created locally
+	MergeParentsStatusTarget StatusTarget = "merge_parents"
+
+	// AllPullRequestCommitsStatusTarget are all commits of the PR
+	AllPullRequestCommitsStatusTarget StatusTarget = "all_pr_commits"
+)
+
+// StatusParams of single build status in statuses list
+type StatusParams struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// AnnotationFormat enumerates formats of tool outputs parsed into Code Insights annotations
+type AnnotationFormat string
+
+const (
+	// JUnitAnnotationFormat annotates failed and errored test cases of JUnit XML
+	JUnitAnnotationFormat AnnotationFormat = "junit"
+
+	// CheckstyleAnnotationFormat annotates errors of Checkstyle XML
+	CheckstyleAnnotationFormat AnnotationFormat = "checkstyle"
+
+	// SARIFAnnotationFormat annotates results of SARIF 2.1 JSON
+	SARIFAnnotationFormat AnnotationFormat = "sarif"
+
+	// GoVetAnnotationFormat annotates diagnostics of `go vet -json`
+	GoVetAnnotationFormat AnnotationFormat = "govet"
+
+	// GolangCILintAnnotationFormat annotates issues of `golangci-lint run --out-format json`
+	GolangCILintAnnotationFormat AnnotationFormat = "golangci-lint"
+)
+
+// ReportAnnotations points tool output files to be uploaded as annotations of the report
+type ReportAnnotations struct {
+	// Path relative to build directory, may be a glob pattern
+	Path   string           `json:"path"`
+	Format AnnotationFormat `json:"format"`
+
+	// StripPrefix is removed from paths reported by the tool to make them relative to the repo root
+	StripPrefix string `json:"strip_prefix"`
+}
+
+// maxReportAnnotations is a limit of annotations per report imposed by BitBucket
+const maxReportAnnotations = 1000
+
+// paramsValidators registers validation of action specific params. Every supported action has to be registered
+var paramsValidators = map[ParamsOutAction]func(p Params) error{
+	CommitBuildStatusSetParamsOutAction: validateCommitBuildStatusParams,
+	CommentAddParamsOutAction:           validateCommentAddParams,
+	ApproveParamsOutAction:              validateNoParams,
+	UnapproveParamsOutAction:            validateNoParams,
+	RequestChangesParamsOutAction:       validateNoParams,
+	MergeParamsOutAction:                validateMergeParams,
+	DeclineParamsOutAction:              validateNoParams,
+	ReportCreateParamsOutAction:         validateReportCreateParams,
+	SupersededStopParamsOutAction:       validateNoParams,
+	PullRequestCreateParamsOutAction:    validatePullRequestCreateParams,
+	ReviewersAddParamsOutAction:         validateReviewersParams,
+	ReviewersRemoveParamsOutAction:      validateReviewersParams,
+	TaskCreateParamsOutAction:           validateTaskCreateParams,
+	TaskResolveParamsOutAction:          validateTaskResolveParams,
+}
+
+// Params object containing configuration of single resource invocation
+type Params struct {
+	RepoPath               string                 `json:"repo_path"`
+	Action                 ParamsOutAction        `json:"action"`
+	Key                    string                 `json:"key"`
+	Status                 string                 `json:"status"`
+	Name                   string                 `json:"name"`
+	Description            string                 `json:"description"`
+	URL                    string                 `json:"url"`
+	StatusFile             string                 `json:"status_file"`
+	DescriptionFile        string                 `json:"description_file"`
+	URLFile                string                 `json:"url_file"`
+	Statuses               []StatusParams         `json:"statuses"`
+	StatusTarget           StatusTarget           `json:"status_target"`
+	Comment                string                 `json:"comment"`
+	CommentFile            string                 `json:"comment_file"`
+	CommentKey             string                 `json:"comment_key"`
+	MergeStrategy          string                 `json:"merge_strategy"`
+	MergeMessage           string                 `json:"merge_message"`
+	CloseSourceBranch      *bool                  `json:"close_source_branch"`
+	PullRequestTitle       string                 `json:"pullrequest_title"`
+	PullRequestDescription string                 `json:"pullrequest_description"`
+	SourceBranch           string                 `json:"source_branch"`
+	DestinationBranch      string                 `json:"destination_branch"`
+	Reviewers              []string               `json:"reviewers"`
+	Task                   string                 `json:"task"`
+	ReportID               string                 `json:"report_id"`
+	ReportTitle            string                 `json:"report_title"`
+	ReportDetails          string                 `json:"report_details"`
+	ReportType             string                 `json:"report_type"`
+	ReportResult           string                 `json:"report_result"`
+	ReportLink             string                 `json:"report_link"`
+	ReportData             []bitbucket.ReportData `json:"report_data"`
+	ReportFile             string                 `json:"report_file"`
+	ReportAnnotations      []ReportAnnotations    `json:"report_annotations"`
+	ReportMaxAnnotations   int                    `json:"report_max_annotations"`
+}
+
+func (p *Params) UnmarshalJSON(data []byte) error {
+	type paramsDefaults Params
+	defaults := &paramsDefaults{
+		Action:               CommitBuildStatusSetParamsOutAction,
+		StatusTarget:         HeadStatusTarget,
+		Name:                 "$BUILD_JOB_NAME #$BUILD_ID",
+		Description:          "Concourse Build CI",
+		ReportMaxAnnotations: maxReportAnnotations,
+	}
+
+	err := json.Unmarshal(data, defaults)
+	if err != nil {
+		return err
+	}
+
+	*p = Params(*defaults)
+
+	return nil
+}
+
+// Validate Params object against required fields of the action
+func (p Params) Validate() error {
+	// PR being created is not yet a version fetched by get step
+	if len(p.RepoPath) == 0 && p.Action != PullRequestCreateParamsOutAction {
+		return errors.New("resource/model: repo path is empty")
+	}
+
+	validate, ok := paramsValidators[p.Action]
+	if !ok {
+		return fmt.Errorf("resource/model: action %s is empty or invalid", p.Action)
+	}
+
+	return validate(p)
+}
+
+func validateNoParams(p Params) error {
+	return nil
+}
+
+func validateCommitBuildStatusParams(p Params) error {
+	switch p.StatusTarget {
+	case HeadStatusTarget, VersionStatusTarget, MergeParentsStatusTarget, AllPullRequestCommitsStatusTarget:
+	default:
+		return fmt.Errorf("resource/model: status target %s is not one of head, version, merge_parents, all_pr_commits", p.StatusTarget)
+	}
+
+	if len(p.Statuses) > 0 {
+		return validateStatusesParams(p.Statuses)
+	}
+
+	if len(p.Status) == 0 && len(p.StatusFile) == 0 {
+		return errors.New("resource/model: status and status file are empty")
+	}
+
+	if len(p.Status) > 0 {
+		err := ValidateCommitBuildStatus(p.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateStatusesParams(statuses []StatusParams) error {
+	keys := make(map[string]bool)
+
+	for _, s := range statuses {
+		if len(s.Key) == 0 {
+			return errors.New("resource/model: key of status in statuses is empty")
+		}
+
+		if keys[s.Key] {
+			return fmt.Errorf("resource/model: key %s is duplicated in statuses", s.Key)
+		}
+
+		keys[s.Key] = true
+
+		err := ValidateCommitBuildStatus(s.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateCommitBuildStatus checks status is one of supported by BitBucket
+func ValidateCommitBuildStatus(status string) error {
+	switch bitbucket.CommitBuildStatus(status) {
+	case bitbucket.SuccessfullCommitBuildStatus, bitbucket.FailedCommitBuildStatus,
+		bitbucket.InProgressCommitBuildStatus, bitbucket.StoppedCommitBuildStatus:
+		return nil
+	default:
+		return fmt.Errorf("resource/model: status %s is not one of SUCCESSFUL, FAILED, INPROGRESS, STOPPED", status)
+	}
+}
+
+func validateCommentAddParams(p Params) error {
+	if len(p.Comment) == 0 && len(p.CommentFile) == 0 {
+		return errors.New("resource/model: comment and comment file are empty")
+	}
+
+	if len(p.Comment) > 0 && len(p.CommentFile) > 0 {
+		return errors.New("resource/model: only one of comment and comment file is allowed")
+	}
+
+	return nil
+}
+
+func validateMergeParams(p Params) error {
+	switch bitbucket.MergeStrategy(p.MergeStrategy) {
+	case "", bitbucket.MergeCommitMergeStrategy, bitbucket.SquashMergeStrategy, bitbucket.FastForwardMergeStrategy:
+	default:
+		return fmt.Errorf("resource/model: merge strategy %s is not one of merge_commit, squash, fast_forward", p.MergeStrategy)
+	}
+
+	return nil
+}
+
+func validateReportCreateParams(p Params) error {
+	if len(p.ReportID) == 0 {
+		return errors.New("resource/model: report id is empty")
+	}
+
+	if len(p.ReportTitle) == 0 && len(p.ReportFile) == 0 {
+		return errors.New("resource/model: report title and report file are empty")
+	}
+
+	if len(p.ReportType) == 0 && len(p.ReportFile) == 0 {
+		return errors.New("resource/model: report type and report file are empty")
+	}
+
+	switch bitbucket.ReportType(p.ReportType) {
+	case "", bitbucket.SecurityReportType, bitbucket.CoverageReportType, bitbucket.TestReportType, bitbucket.BugReportType:
+	default:
+		return fmt.Errorf("resource/model: report type %s is not one of SECURITY, COVERAGE, TEST, BUG", p.ReportType)
+	}
+
+	switch bitbucket.ReportResult(p.ReportResult) {
+	case "", bitbucket.PassedReportResult, bitbucket.FailedReportResult, bitbucket.PendingReportResult:
+	default:
+		return fmt.Errorf("resource/model: report result %s is not one of PASSED, FAILED, PENDING", p.ReportResult)
+	}
+
+	for _, d := range p.ReportData {
+		switch d.Type {
+		case bitbucket.BooleanReportDataType, bitbucket.DateReportDataType, bitbucket.DurationReportDataType,
+			bitbucket.LinkReportDataType, bitbucket.NumberReportDataType, bitbucket.PercentageReportDataType,
+			bitbucket.TextReportDataType:
+		default:
+			return fmt.Errorf("resource/model: report data %s type %s is invalid", d.Title, d.Type)
+		}
+	}
+
+	for _, a := range p.ReportAnnotations {
+		if len(a.Path) == 0 {
+			return errors.New("resource/model: report annotations path is empty")
+		}
+
+		switch a.Format {
+		case JUnitAnnotationFormat, CheckstyleAnnotationFormat, SARIFAnnotationFormat,
+			GoVetAnnotationFormat, GolangCILintAnnotationFormat:
+		default:
+			return fmt.Errorf("resource/model: report annotations format %s is not one of junit, checkstyle, sarif, govet, golangci-lint", a.Format)
+		}
+	}
+
+	if p.ReportMaxAnnotations < 0 || p.ReportMaxAnnotations > maxReportAnnotations {
+		return fmt.Errorf("resource/model: report max annotations %d is not within 0-%d", p.ReportMaxAnnotations, maxReportAnnotations)
+	}
+
+	return nil
+}
+
+func validatePullRequestCreateParams(p Params) error {
+	if len(p.PullRequestTitle) == 0 {
+		return errors.New("resource/model: pullrequest title is empty")
+	}
+
+	if len(p.SourceBranch) == 0 || len(p.DestinationBranch) == 0 {
+		return errors.New("resource/model: source or destination branch is empty")
+	}
+
+	return nil
+}
+
+func validateReviewersParams(p Params) error {
+	if len(p.Reviewers) == 0 {
+		return errors.New("resource/model: reviewers are empty")
+	}
+
+	for _, r := range p.Reviewers {
+		// groups are only available in deprecated API 1.0, which is being removed
+		if strings.HasPrefix(r, "group:") {
+			return fmt.Errorf("resource/model: reviewer %s is a group, which is not supported by BitBucket API 2.0, list its members instead", r)
+		}
+	}
+
+	return nil
+}
+
+func validateTaskCreateParams(p Params) error {
+	if len(p.Task) == 0 {
+		return errors.New("resource/model: task is empty")
+	}
+
+	return nil
+}
+
+func validateTaskResolveParams(p Params) error {
+	if len(p.Task) == 0 && len(p.CommentKey) == 0 {
+		return errors.New("resource/model: task and comment key are empty")
+	}
+
+	return nil
+}
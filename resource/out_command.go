@@ -2,9 +2,9 @@ package resource
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 
 	git "github.com/libgit2/git2go/v31"
 	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
@@ -12,13 +12,13 @@ import (
 	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
 )
 
-// OutCommand sets PullRequest status and metadata to passed one in OutRequest struct
+// OutCommand performs action on PullRequest of the version stored by previous get step, ie. sets build status
 type OutCommand struct {
 	Logger *concourse.Logger
 }
 
 // Run OutCommand processing.
-// Full SHA1 is fetched from HEAD of previous checkout step
+// Action is dispatched according to params, see outActions
 func (cmd *OutCommand) Run(req models.OutRequest, destination string) (*models.OutResponse, error) {
 	err := req.Source.Validate()
 	if err != nil {
@@ -30,53 +30,130 @@ func (cmd *OutCommand) Run(req models.OutRequest, destination string) (*models.O
 		return nil, fmt.Errorf("resource/out: params invalid: %w", err)
 	}
 
-	versionPath := filepath.Join(req.Params.RepoPath, string(concourse.VersionStorageFilename))
+	var version models.Version
 
-	cmd.Logger.Debugf("resource/out: reading version from %s", versionPath)
+	if len(req.Params.RepoPath) > 0 {
+		versionPath := filepath.Join(req.Params.RepoPath, string(concourse.VersionStorageFilename))
 
-	var version models.Version
+		cmd.Logger.Debugf("resource/out: reading version from %s", versionPath)
 
-	err = concourse.NewStorage(destination, versionPath).Read(&version)
+		err = concourse.NewStorage(destination, versionPath).Read(&version)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: version read: %w", err)
+		}
+
+		cmd.Logger.Debugf("resource/out: version with commit %s, id %s", version.Ref, version.ID)
+	}
+
+	trans, err := newTransport(req.Source)
 	if err != nil {
-		return nil, fmt.Errorf("resource/out: version read: %w", err)
+		return nil, fmt.Errorf("resource/out: transport: %w", err)
+	}
+
+	action, ok := outActions[req.Params.Action]
+	if !ok {
+		return nil, fmt.Errorf("resource/out: action %s not supported", req.Params.Action)
 	}
 
-	cmd.Logger.Debugf("resource/out: version with commit %s, id %s", version.Ref, version.ID)
+	cmd.Logger.Debugf("resource/out: perform action %s", req.Params.Action)
 
-	path := filepath.Join(destination, req.Params.RepoPath)
+	ctx := &outContext{
+		params:   req.Params,
+		version:  version,
+		client:   trans.BitbucketClient(),
+		repoPath: filepath.Join(destination, req.Params.RepoPath),
+		workDir:  destination,
+	}
 
-	hash, err := cmd.gitGetHeadHash(path)
+	metadata, err := action(cmd, ctx)
 	if err != nil {
-		return nil, fmt.Errorf("resource/out: get git head: %w", err)
+		return nil, fmt.Errorf("resource/out: %s: %w", req.Params.Action, err)
 	}
 
-	cmd.Logger.Debugf("resource/out: got commit SHA1: %s", hash)
+	// action may emit a new version, ie. of created PR
+	return &models.OutResponse{
+		Version:  ctx.version,
+		Metadata: metadata,
+	}, nil
+}
+
+// outContext carries state shared by actions of OutCommand
+type outContext struct {
+	params   models.Params
+	version  models.Version
+	client   *bitbucket.Client
+	repoPath string
+	workDir  string
+
+	// pr is cached by pullRequest
+	pr *bitbucket.PullRequestEntity
+}
+
+// pullRequest of the version, fetched once per invocation
+func (ctx *outContext) pullRequest() (*bitbucket.PullRequestEntity, error) {
+	if ctx.pr != nil {
+		return ctx.pr, nil
+	}
 
-	auth := bitbucket.Auth{
-		Username: req.Source.Username,
-		Password: req.Source.Password,
+	pr, err := ctx.client.GetPullRequest(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get PR: %w", err)
 	}
 
-	client := bitbucket.NewClient(req.Source.Workspace, req.Source.Slug, &auth)
+	ctx.pr = pr
 
-	statReq := bitbucket.CommitBuildStatusRequest{
-		Key:         substituteEnvs(req.Params.Key),
-		Name:        substituteEnvs(req.Params.Name),
-		Description: substituteEnvs(req.Params.Description),
-		URL:         substituteEnvs(req.Params.URL),
-		State:       bitbucket.CommitBuildStatus(req.Params.Status),
+	return pr, nil
+}
+
+// readFile at path relative to the build directory, which contains outputs of previous steps
+func (ctx outContext) readFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(ctx.workDir, path))
+	if err != nil {
+		return "", fmt.Errorf("resource/out: read file: %w", err)
 	}
 
-	cmd.Logger.Debugf("resource/out: set status %s", statReq.State)
+	return string(content), nil
+}
+
+// outAction performs single action on PR or commit of the resource version
+type outAction func(cmd *OutCommand, ctx *outContext) (models.Metadata, error)
+
+// outActions registers handlers of actions supported by OutCommand
+var outActions = map[models.ParamsOutAction]outAction{
+	models.CommitBuildStatusSetParamsOutAction: (*OutCommand).setCommitBuildStatus,
+	models.CommentAddParamsOutAction:           (*OutCommand).addComment,
+	models.ApproveParamsOutAction:              (*OutCommand).approve,
+	models.UnapproveParamsOutAction:            (*OutCommand).unapprove,
+	models.RequestChangesParamsOutAction:       (*OutCommand).requestChanges,
+	models.MergeParamsOutAction:                (*OutCommand).merge,
+	models.DeclineParamsOutAction:              (*OutCommand).decline,
+	models.ReportCreateParamsOutAction:         (*OutCommand).createReport,
+	models.SupersededStopParamsOutAction:       (*OutCommand).stopSuperseded,
+	models.PullRequestCreateParamsOutAction:    (*OutCommand).createPullRequest,
+	models.ReviewersAddParamsOutAction:         (*OutCommand).addReviewers,
+	models.ReviewersRemoveParamsOutAction:      (*OutCommand).removeReviewers,
+	models.TaskCreateParamsOutAction:           (*OutCommand).createTask,
+	models.TaskResolveParamsOutAction:          (*OutCommand).resolveTasks,
+}
 
-	err = client.SetCommitBuildStatus(hash, &statReq)
+// commitHash of the version checked out by previous get step.
+// Full SHA1 is fetched from HEAD of the repo or from stored version if repo was not downloaded
+func (cmd *OutCommand) commitHash(ctx *outContext) (string, error) {
+	_, err := os.Stat(filepath.Join(ctx.repoPath, ".git"))
+	if os.IsNotExist(err) {
+		// get step with skip_download or archive format leaves no repo, stored version points the commit
+		cmd.Logger.Debugf("resource/out: no git repo at %s, using commit of version", ctx.repoPath)
+		return ctx.version.Ref, nil
+	}
+
+	hash, err := cmd.gitGetHeadHash(ctx.repoPath)
 	if err != nil {
-		return nil, fmt.Errorf("resource/out: set build status %w", err)
+		return "", err
 	}
 
-	return &models.OutResponse{
-		Version: version,
-	}, nil
+	cmd.Logger.Debugf("resource/out: got commit SHA1: %s", hash)
+
+	return hash, nil
 }
 
 func (cmd *OutCommand) gitGetHeadHash(path string) (string, error) {
@@ -93,11 +170,65 @@ func (cmd *OutCommand) gitGetHeadHash(path string) (string, error) {
 	return head.Target().String(), nil
 }
 
-func substituteEnvs(s string) string {
-	for _, item := range os.Environ() {
-		kv := strings.Split(item, "=")
-		s = strings.ReplaceAll(s, "$"+kv[0], kv[1])
+func (cmd *OutCommand) gitGetHeadParents(path string) ([]string, error) {
+	repo, err := git.OpenRepository(path)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: open repo at path %s: %w", path, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get head: %w", err)
+	}
+
+	commit, err := repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: lookup head commit: %w", err)
+	}
+
+	parents := make([]string, 0, commit.ParentCount())
+	for i := uint(0); i < commit.ParentCount(); i++ {
+		parents = append(parents, commit.ParentId(i).String())
+	}
+
+	return parents, nil
+}
+
+// gitNotReachableFrom filters hashes, which are neither the commit nor its ancestors.
+// Commit may be abbreviated
+func (cmd *OutCommand) gitNotReachableFrom(path string, hashes []string, commit string) ([]string, error) {
+	repo, err := git.OpenRepository(path)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: open repo at path %s: %w", path, err)
+	}
+
+	obj, err := repo.RevparseSingle(commit)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: lookup commit %s: %w", commit, err)
+	}
+
+	tip := obj.Id()
+	result := make([]string, 0, len(hashes))
+
+	for _, h := range hashes {
+		oid, err := git.NewOid(h)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: parse hash %s: %w", h, err)
+		}
+
+		if oid.Equal(tip) {
+			continue
+		}
+
+		reachable, err := repo.DescendantOf(tip, oid)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: check %s is ancestor of %s: %w", h, commit, err)
+		}
+
+		if !reachable {
+			result = append(result, h)
+		}
 	}
 
-	return s
+	return result, nil
 }
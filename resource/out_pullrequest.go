@@ -0,0 +1,271 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// addComment to PR of the version. Comment carrying marker of the same comment key is updated instead
+func (cmd *OutCommand) addComment(ctx *outContext) (models.Metadata, error) {
+	markdown := ctx.params.Comment
+
+	err := ctx.render(&markdown)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ctx.params.CommentFile) > 0 {
+		content, err := ctx.readFile(ctx.params.CommentFile)
+		if err != nil {
+			return nil, err
+		}
+
+		markdown = content
+	}
+
+	var comment *bitbucket.CommentEntity
+
+	if len(ctx.params.CommentKey) > 0 {
+		marker := commentMarker(ctx.params.CommentKey)
+		markdown = markdown + "\n\n" + marker
+
+		comment, err = cmd.findComment(ctx, marker)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if comment != nil {
+		cmd.Logger.Debugf("resource/out: update comment %d of PR %s", comment.ID, ctx.version.ID)
+
+		comment, err = ctx.client.UpdatePullRequestComment(ctx.version.ID, comment.ID, markdown)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: update comment: %w", err)
+		}
+	} else {
+		cmd.Logger.Debugf("resource/out: add comment to PR %s", ctx.version.ID)
+
+		comment, err = ctx.client.AddPullRequestComment(ctx.version.ID, markdown)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: add comment: %w", err)
+		}
+	}
+
+	return models.Metadata{
+		{Name: models.CommentMetadataName, Value: strconv.Itoa(comment.ID)},
+	}, nil
+}
+
+// commentMarker is a Markdown reference, not rendered by BitBucket, which identifies sticky comment
+func commentMarker(key string) string {
+	return fmt.Sprintf("[//]: # (concourse-bitbucket-pr:%s)", key)
+}
+
+// findComment of PR containing marker. Nil is returned if there is none
+func (cmd *OutCommand) findComment(ctx *outContext, marker string) (*bitbucket.CommentEntity, error) {
+	comments, err := ctx.client.GetPullRequestComments(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get comments: %w", err)
+	}
+
+	for _, c := range comments {
+		if !c.Deleted && strings.Contains(c.Content.Raw, marker) {
+			comment := c
+			return &comment, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// approve PR of the version
+func (cmd *OutCommand) approve(ctx *outContext) (models.Metadata, error) {
+	cmd.Logger.Debugf("resource/out: approve PR %s", ctx.version.ID)
+
+	err := ctx.client.ApprovePullRequest(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: approve: %w", err)
+	}
+
+	return models.Metadata{}, nil
+}
+
+// unapprove PR of the version
+func (cmd *OutCommand) unapprove(ctx *outContext) (models.Metadata, error) {
+	cmd.Logger.Debugf("resource/out: unapprove PR %s", ctx.version.ID)
+
+	err := ctx.client.UnapprovePullRequest(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: unapprove: %w", err)
+	}
+
+	return models.Metadata{}, nil
+}
+
+// requestChanges in PR of the version
+func (cmd *OutCommand) requestChanges(ctx *outContext) (models.Metadata, error) {
+	cmd.Logger.Debugf("resource/out: request changes in PR %s", ctx.version.ID)
+
+	err := ctx.client.RequestChangesPullRequest(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: request changes: %w", err)
+	}
+
+	return models.Metadata{}, nil
+}
+
+// merge PR of the version. Aborts if PR head moved since the version, as the newer commits were not built
+func (cmd *OutCommand) merge(ctx *outContext) (models.Metadata, error) {
+	pr, err := ctx.pullRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	if pr.State != bitbucket.OpenPullRequestState {
+		return nil, fmt.Errorf("resource/out: PR %s is %s, not open", ctx.version.ID, pr.State)
+	}
+
+	if !sameCommit(pr.Source.Commit.Hash, ctx.version.Ref) {
+		return nil, fmt.Errorf("resource/out: PR head moved from %s to %s since the version", ctx.version.Ref, pr.Source.Commit.Hash)
+	}
+
+	closeSourceBranch := pr.CloseAfterMerge
+	if ctx.params.CloseSourceBranch != nil {
+		closeSourceBranch = *ctx.params.CloseSourceBranch
+	}
+
+	message := ctx.params.MergeMessage
+
+	err = ctx.render(&message)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Logger.Debugf("resource/out: merge PR %s", ctx.version.ID)
+
+	merged, err := ctx.client.MergePullRequest(ctx.version.ID, &bitbucket.MergeRequest{
+		Message:           message,
+		CloseSourceBranch: closeSourceBranch,
+		MergeStrategy:     bitbucket.MergeStrategy(ctx.params.MergeStrategy),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: merge: %w", err)
+	}
+
+	metadata := pullRequestStateMetadata(merged)
+	if merged.MergeCommit != nil {
+		metadata = append(metadata, models.MetadataField{Name: models.MergeCommitMetadataName, Value: merged.MergeCommit.Hash})
+	}
+
+	return metadata, nil
+}
+
+// sameCommit compares hashes, any of which may be abbreviated as returned by BitBucket API
+func sameCommit(a, b string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// decline PR of the version
+func (cmd *OutCommand) decline(ctx *outContext) (models.Metadata, error) {
+	cmd.Logger.Debugf("resource/out: decline PR %s", ctx.version.ID)
+
+	pr, err := ctx.client.DeclinePullRequest(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: decline: %w", err)
+	}
+
+	return pullRequestStateMetadata(pr), nil
+}
+
+func pullRequestStateMetadata(pr *bitbucket.PullRequestEntity) models.Metadata {
+	return models.Metadata{
+		{Name: models.StateMetadataName, Value: string(pr.State)},
+	}
+}
+
+// createPullRequest of params branches, unless there is already open one, and emit it as the version
+func (cmd *OutCommand) createPullRequest(ctx *outContext) (models.Metadata, error) {
+	title, description := ctx.params.PullRequestTitle, ctx.params.PullRequestDescription
+	source, dest := ctx.params.SourceBranch, ctx.params.DestinationBranch
+
+	err := ctx.render(&title, &description, &source, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := cmd.findOpenPullRequest(ctx, source, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr != nil {
+		cmd.Logger.Debugf("resource/out: PR %d of %s to %s already open", pr.ID, source, dest)
+	} else {
+		createReq := bitbucket.CreatePullRequestRequest{
+			Title:       title,
+			Description: description,
+			Source:      bitbucket.BranchReference{Branch: bitbucket.GitBranch{Name: source}},
+			Dest:        bitbucket.BranchReference{Branch: bitbucket.GitBranch{Name: dest}},
+		}
+
+		if ctx.params.CloseSourceBranch != nil {
+			createReq.CloseSourceBranch = *ctx.params.CloseSourceBranch
+		}
+
+		for _, r := range ctx.params.Reviewers {
+			createReq.Reviewers = append(createReq.Reviewers, bitbucket.NewUserReference(r))
+		}
+
+		cmd.Logger.Debugf("resource/out: create PR of %s to %s", source, dest)
+
+		pr, err = ctx.client.CreatePullRequest(&createReq)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: create PR: %w", err)
+		}
+	}
+
+	// PR entity carries abbreviated hash, while versions point full one
+	commit, err := ctx.client.GetCommit(pr.Source.Commit.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get PR head commit: %w", err)
+	}
+
+	ctx.version = models.Version{
+		Ref: commit.Hash,
+		ID:  strconv.Itoa(pr.ID),
+	}
+	ctx.pr = pr
+
+	return models.Metadata{
+		{Name: models.TitleMetadataName, Value: pr.Title},
+		{Name: models.StateMetadataName, Value: string(pr.State)},
+		{Name: models.SourceBranchMetadataName, Value: pr.Source.Branch.Name},
+		{Name: models.DestinationBranchMetadataName, Value: pr.Dest.Branch.Name},
+		{Name: models.PullrequestURLMetadataName, Value: ctx.client.PullrequestURL(ctx.version.ID)},
+	}, nil
+}
+
+// findOpenPullRequest of the branches. Nil is returned if there is none
+func (cmd *OutCommand) findOpenPullRequest(ctx *outContext, source, dest string) (*bitbucket.PullRequestEntity, error) {
+	prs, err := ctx.client.GetPullRequestsPaged()
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get open PRs: %w", err)
+	}
+
+	for _, pr := range prs {
+		if pr.State == bitbucket.OpenPullRequestState && pr.Source.Branch.Name == source && pr.Dest.Branch.Name == dest {
+			found := pr
+			return &found, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,154 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/annotations"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+const reportReporter = "Concourse"
+
+// createReport of Code Insights on the commit checked out by previous get step.
+// Report read from report_file, if any, is completed with fields set in params
+func (cmd *OutCommand) createReport(ctx *outContext) (models.Metadata, error) {
+	hash, err := cmd.commitHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, title, details, link := ctx.params.ReportID, ctx.params.ReportTitle, ctx.params.ReportDetails, ctx.params.ReportLink
+
+	err = ctx.render(&id, &title, &details, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	report := bitbucket.Report{
+		Reporter: reportReporter,
+	}
+
+	if len(ctx.params.ReportFile) > 0 {
+		content, err := ctx.readFile(ctx.params.ReportFile)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(content), &report)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: decode report file %s: %w", ctx.params.ReportFile, err)
+		}
+	}
+
+	if len(title) > 0 {
+		report.Title = title
+	}
+
+	if len(details) > 0 {
+		report.Details = details
+	}
+
+	if len(ctx.params.ReportType) > 0 {
+		report.ReportType = bitbucket.ReportType(ctx.params.ReportType)
+	}
+
+	if len(ctx.params.ReportResult) > 0 {
+		report.Result = bitbucket.ReportResult(ctx.params.ReportResult)
+	}
+
+	if len(link) > 0 {
+		report.Link = link
+	}
+
+	report.Data = append(report.Data, ctx.params.ReportData...)
+
+	if len(report.Title) == 0 {
+		return nil, fmt.Errorf("resource/out: report %s has no title", id)
+	}
+
+	if len(report.ReportType) == 0 {
+		return nil, fmt.Errorf("resource/out: report %s has no type", id)
+	}
+
+	// tool outputs are parsed before the report is created, not to leave report without annotations
+	found, err := cmd.readAnnotations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Logger.Debugf("resource/out: create report %s with %d data fields", id, len(report.Data))
+
+	err = ctx.client.CreateReport(hash, id, &report)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: create report: %w", err)
+	}
+
+	if len(found) > 0 {
+		cmd.Logger.Debugf("resource/out: create %d annotations of report %s", len(found), id)
+
+		err = ctx.client.CreateAnnotations(hash, id, found)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: create annotations: %w", err)
+		}
+	}
+
+	return models.Metadata{
+		{Name: models.CommitMetadataName, Value: hash},
+		{Name: models.ReportMetadataName, Value: id},
+		{Name: models.AnnotationsMetadataName, Value: strconv.Itoa(len(found))},
+	}, nil
+}
+
+// readAnnotations from tool outputs matching report_annotations, capped at report_max_annotations
+func (cmd *OutCommand) readAnnotations(ctx *outContext) ([]bitbucket.Annotation, error) {
+	all := make([]bitbucket.Annotation, 0)
+
+	for _, a := range ctx.params.ReportAnnotations {
+		paths, err := filepath.Glob(filepath.Join(ctx.workDir, a.Path))
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: annotations path %s: %w", a.Path, err)
+		}
+
+		if len(paths) == 0 {
+			cmd.Logger.Debugf("resource/out: no annotations file matches %s", a.Path)
+		}
+
+		for _, path := range paths {
+			parsed, err := parseAnnotationsFile(a, path)
+			if err != nil {
+				return nil, err
+			}
+
+			cmd.Logger.Debugf("resource/out: parsed %d annotations from %s", len(parsed), path)
+
+			all = append(all, parsed...)
+		}
+	}
+
+	limited := annotations.Limit(all, ctx.params.ReportMaxAnnotations)
+	if len(limited) < len(all) {
+		cmd.Logger.Debugf("resource/out: dropped %d annotations over the limit", len(all)-len(limited))
+	}
+
+	return limited, nil
+}
+
+func parseAnnotationsFile(a models.ReportAnnotations, path string) ([]bitbucket.Annotation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: open annotations file: %w", err)
+	}
+	defer f.Close()
+
+	parsed, err := annotations.Parse(a.Format, a.StripPrefix, f)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: annotations file %s: %w", path, err)
+	}
+
+	return parsed, nil
+}
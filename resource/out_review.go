@@ -0,0 +1,252 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// errNoTaskComment is reported when there is no sticky comment of comment_key to link tasks to
+var errNoTaskComment = errors.New("resource/out: no comment of comment key to link task to")
+
+// addReviewers to PR of the version. PR author is skipped, as BitBucket rejects author as reviewer
+func (cmd *OutCommand) addReviewers(ctx *outContext) (models.Metadata, error) {
+	pr, err := ctx.pullRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	added := cmd.resolveReviewers(ctx)
+
+	author := bitbucket.UserReference{UUID: pr.Author.UUID, AccountID: pr.Author.AccountID}
+
+	return cmd.updateReviewers(ctx, pr, mergeReviewers(currentReviewers(pr), added, author))
+}
+
+// removeReviewers from PR of the version
+func (cmd *OutCommand) removeReviewers(ctx *outContext) (models.Metadata, error) {
+	pr, err := ctx.pullRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := cmd.resolveReviewers(ctx)
+
+	return cmd.updateReviewers(ctx, pr, withoutReviewers(currentReviewers(pr), removed))
+}
+
+func (cmd *OutCommand) updateReviewers(ctx *outContext, pr *bitbucket.PullRequestEntity, reviewers []bitbucket.UserReference) (models.Metadata, error) {
+	cmd.Logger.Debugf("resource/out: set %d reviewers of PR %s", len(reviewers), ctx.version.ID)
+
+	updated, err := ctx.client.UpdatePullRequestReviewers(ctx.version.ID, pr.Title, reviewers)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: update reviewers: %w", err)
+	}
+
+	names := make([]string, 0, len(updated.Reviewers))
+	for _, r := range updated.Reviewers {
+		names = append(names, r.Name)
+	}
+
+	return models.Metadata{
+		{Name: models.ReviewersMetadataName, Value: strings.Join(names, ", ")},
+	}, nil
+}
+
+// resolveReviewers of params, identified by UUID or account ID
+func (cmd *OutCommand) resolveReviewers(ctx *outContext) []bitbucket.UserReference {
+	reviewers := make([]bitbucket.UserReference, 0, len(ctx.params.Reviewers))
+
+	for _, r := range ctx.params.Reviewers {
+		reviewers = append(reviewers, bitbucket.NewUserReference(r))
+	}
+
+	return reviewers
+}
+
+func currentReviewers(pr *bitbucket.PullRequestEntity) []bitbucket.UserReference {
+	reviewers := make([]bitbucket.UserReference, 0, len(pr.Reviewers))
+
+	for _, r := range pr.Reviewers {
+		reviewers = append(reviewers, bitbucket.UserReference{UUID: r.UUID, AccountID: r.AccountID})
+	}
+
+	return reviewers
+}
+
+// mergeReviewers appends added users not yet among current ones, skipping author
+func mergeReviewers(current, added []bitbucket.UserReference, author bitbucket.UserReference) []bitbucket.UserReference {
+	reviewers := append(make([]bitbucket.UserReference, 0, len(current)+len(added)), current...)
+
+	for _, r := range added {
+		if sameUser(r, author) || containsUser(reviewers, r) {
+			continue
+		}
+
+		reviewers = append(reviewers, r)
+	}
+
+	return reviewers
+}
+
+// withoutReviewers filters out removed users from current ones
+func withoutReviewers(current, removed []bitbucket.UserReference) []bitbucket.UserReference {
+	reviewers := make([]bitbucket.UserReference, 0, len(current))
+
+	for _, r := range current {
+		if !containsUser(removed, r) {
+			reviewers = append(reviewers, r)
+		}
+	}
+
+	return reviewers
+}
+
+func containsUser(users []bitbucket.UserReference, user bitbucket.UserReference) bool {
+	for _, u := range users {
+		if sameUser(u, user) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameUser(a, b bitbucket.UserReference) bool {
+	return (len(a.UUID) > 0 && a.UUID == b.UUID) || (len(a.AccountID) > 0 && a.AccountID == b.AccountID)
+}
+
+// createTask in PR of the version, linked to the sticky comment of comment_key if set.
+// Task is not duplicated if the same one is still unresolved
+func (cmd *OutCommand) createTask(ctx *outContext) (models.Metadata, error) {
+	content := ctx.params.Task
+
+	err := ctx.render(&content)
+	if err != nil {
+		return nil, err
+	}
+
+	commentID, err := cmd.taskCommentID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := ctx.client.GetPullRequestTasks(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get tasks: %w", err)
+	}
+
+	if existing := unresolvedTasks(tasks, content, commentID); len(existing) > 0 {
+		cmd.Logger.Debugf("resource/out: task %d already unresolved", existing[0].ID)
+
+		return models.Metadata{
+			{Name: models.TaskMetadataName, Value: strconv.Itoa(existing[0].ID)},
+		}, nil
+	}
+
+	cmd.Logger.Debugf("resource/out: create task in PR %s", ctx.version.ID)
+
+	task, err := ctx.client.CreatePullRequestTask(ctx.version.ID, content, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: create task: %w", err)
+	}
+
+	return models.Metadata{
+		{Name: models.TaskMetadataName, Value: strconv.Itoa(task.ID)},
+	}, nil
+}
+
+// resolveTasks of PR of the version, matching task content and linked to the sticky comment of comment_key, whichever set
+func (cmd *OutCommand) resolveTasks(ctx *outContext) (models.Metadata, error) {
+	content := ctx.params.Task
+
+	err := ctx.render(&content)
+	if err != nil {
+		return nil, err
+	}
+
+	commentID, err := cmd.taskCommentID(ctx)
+	if errors.Is(err, errNoTaskComment) {
+		// without the comment there are no tasks linked to it
+		cmd.Logger.Debugf("%s, nothing to resolve", err)
+
+		return models.Metadata{
+			{Name: models.ResolvedMetadataName, Value: "0"},
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := ctx.client.GetPullRequestTasks(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get tasks: %w", err)
+	}
+
+	resolved := 0
+
+	for _, t := range unresolvedTasks(tasks, content, commentID) {
+		cmd.Logger.Debugf("resource/out: resolve task %d", t.ID)
+
+		_, err = ctx.client.ResolvePullRequestTask(ctx.version.ID, t)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: resolve task %d: %w", t.ID, err)
+		}
+
+		resolved++
+	}
+
+	return models.Metadata{
+		{Name: models.ResolvedMetadataName, Value: strconv.Itoa(resolved)},
+	}, nil
+}
+
+// taskCommentID of the sticky comment of comment_key, 0 if key is not set
+func (cmd *OutCommand) taskCommentID(ctx *outContext) (int, error) {
+	if len(ctx.params.CommentKey) == 0 {
+		return 0, nil
+	}
+
+	comment, err := cmd.findComment(ctx, commentMarker(ctx.params.CommentKey))
+	if err != nil {
+		return 0, err
+	}
+
+	if comment == nil {
+		return 0, errNoTaskComment
+	}
+
+	return comment.ID, nil
+}
+
+// unresolvedTasks with the content linked to the comment. Content and comment are not matched when empty or 0
+func unresolvedTasks(tasks []bitbucket.TaskEntity, content string, commentID int) []bitbucket.TaskEntity {
+	matching := make([]bitbucket.TaskEntity, 0)
+
+	for _, t := range tasks {
+		if t.State != bitbucket.UnresolvedTaskState || !taskLinkedTo(t, commentID) {
+			continue
+		}
+
+		if len(content) > 0 && t.Content.Raw != content {
+			continue
+		}
+
+		matching = append(matching, t)
+	}
+
+	return matching
+}
+
+// taskLinkedTo comment, any task matches if commentID is 0
+func taskLinkedTo(t bitbucket.TaskEntity, commentID int) bool {
+	if commentID == 0 {
+		return true
+	}
+
+	return t.Comment != nil && t.Comment.ID == commentID
+}
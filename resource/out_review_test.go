@@ -0,0 +1,107 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+)
+
+func TestMergeReviewers(t *testing.T) {
+	alice := bitbucket.UserReference{UUID: "{a1}", AccountID: "557058:a1"}
+	bob := bitbucket.UserReference{UUID: "{b2}", AccountID: "557058:b2"}
+	author := bitbucket.UserReference{UUID: "{c3}", AccountID: "557058:c3"}
+
+	cases := []struct {
+		name     string
+		current  []bitbucket.UserReference
+		added    []bitbucket.UserReference
+		expected []bitbucket.UserReference
+	}{
+		{"new", []bitbucket.UserReference{alice}, []bitbucket.UserReference{bob}, []bitbucket.UserReference{alice, bob}},
+		{"existing by uuid", []bitbucket.UserReference{alice}, []bitbucket.UserReference{{UUID: "{a1}"}}, []bitbucket.UserReference{alice}},
+		{"existing by account id", []bitbucket.UserReference{alice}, []bitbucket.UserReference{{AccountID: "557058:a1"}}, []bitbucket.UserReference{alice}},
+		{"duplicated in added", nil, []bitbucket.UserReference{{UUID: "{b2}"}, bob}, []bitbucket.UserReference{{UUID: "{b2}"}}},
+		{"author skipped", nil, []bitbucket.UserReference{{AccountID: "557058:c3"}, bob}, []bitbucket.UserReference{bob}},
+		{"different ids", []bitbucket.UserReference{{UUID: "{a1}"}}, []bitbucket.UserReference{{AccountID: "557058:a1"}},
+			[]bitbucket.UserReference{{UUID: "{a1}"}, {AccountID: "557058:a1"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			merged := mergeReviewers(c.current, c.added, author)
+			if !reflect.DeepEqual(merged, c.expected) {
+				t.Errorf("expected %+v, got %+v", c.expected, merged)
+			}
+		})
+	}
+}
+
+func TestWithoutReviewers(t *testing.T) {
+	alice := bitbucket.UserReference{UUID: "{a1}", AccountID: "557058:a1"}
+	bob := bitbucket.UserReference{UUID: "{b2}", AccountID: "557058:b2"}
+
+	cases := []struct {
+		name     string
+		removed  []bitbucket.UserReference
+		expected []bitbucket.UserReference
+	}{
+		{"by uuid", []bitbucket.UserReference{bitbucket.NewUserReference("{a1}")}, []bitbucket.UserReference{bob}},
+		{"by account id", []bitbucket.UserReference{bitbucket.NewUserReference("557058:b2")}, []bitbucket.UserReference{alice}},
+		{"not a reviewer", []bitbucket.UserReference{bitbucket.NewUserReference("{c3}")}, []bitbucket.UserReference{alice, bob}},
+		{"all", []bitbucket.UserReference{bob, alice}, []bitbucket.UserReference{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reviewers := withoutReviewers([]bitbucket.UserReference{alice, bob}, c.removed)
+			if !reflect.DeepEqual(reviewers, c.expected) {
+				t.Errorf("expected %+v, got %+v", c.expected, reviewers)
+			}
+		})
+	}
+}
+
+func TestUnresolvedTasks(t *testing.T) {
+	task := func(id int, content string, state bitbucket.TaskState, commentID int) bitbucket.TaskEntity {
+		e := bitbucket.TaskEntity{ID: id, Content: bitbucket.CommentContent{Raw: content}, State: state}
+		if commentID > 0 {
+			e.Comment = &bitbucket.TaskComment{ID: commentID}
+		}
+		return e
+	}
+
+	tasks := []bitbucket.TaskEntity{
+		task(1, "Fix lint", bitbucket.UnresolvedTaskState, 10),
+		task(2, "Fix lint", bitbucket.ResolvedTaskState, 10),
+		task(3, "Fix tests", bitbucket.UnresolvedTaskState, 10),
+		task(4, "Fix lint", bitbucket.UnresolvedTaskState, 0),
+		task(5, "Fix lint", bitbucket.UnresolvedTaskState, 20),
+	}
+
+	cases := []struct {
+		name      string
+		content   string
+		commentID int
+		expected  []int
+	}{
+		{"content and comment", "Fix lint", 10, []int{1}},
+		{"comment only", "", 10, []int{1, 3}},
+		{"content only", "Fix lint", 0, []int{1, 4, 5}},
+		{"any", "", 0, []int{1, 3, 4, 5}},
+		{"other comment", "Fix tests", 20, []int{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ids := make([]int, 0)
+			for _, task := range unresolvedTasks(tasks, c.content, c.commentID) {
+				ids = append(ids, task.ID)
+			}
+
+			if !reflect.DeepEqual(ids, c.expected) {
+				t.Errorf("expected tasks %v, got %v", c.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// setCommitBuildStatus on the commits of status target, by default checked out by previous get step.
+// Multiple statuses are submitted concurrently, failure of one does not stop the others
+func (cmd *OutCommand) setCommitBuildStatus(ctx *outContext) (models.Metadata, error) {
+	hashes, err := cmd.statusTargetHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := cmd.buildStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]commitStatus, 0, len(hashes)*len(statuses))
+
+	for _, hash := range hashes {
+		for j := range statuses {
+			targets = append(targets, commitStatus{hash: hash, status: &statuses[j]})
+		}
+	}
+
+	err = cmd.submitStatuses(ctx, targets)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: set build status: %w", err)
+	}
+
+	return models.Metadata{
+		{Name: models.CommitMetadataName, Value: strings.Join(hashes, ", ")},
+	}, nil
+}
+
+// commitStatus to be set on the commit of hash
+type commitStatus struct {
+	hash   string
+	status *bitbucket.CommitBuildStatusRequest
+}
+
+// maxConcurrentStatuses submitted at once, not to hit API rate limits with many commits or statuses
+const maxConcurrentStatuses = 6
+
+// submitStatuses concurrently, failure of one does not stop the others. Errors are aggregated
+func (cmd *OutCommand) submitStatuses(ctx *outContext, targets []commitStatus) error {
+	errs := make([]error, len(targets))
+	slots := make(chan struct{}, maxConcurrentStatuses)
+
+	var wg sync.WaitGroup
+
+	for i, t := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(n int, t commitStatus) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			cmd.Logger.Debugf("resource/out: set status %s with key %s on %s", t.status.State, t.status.Key, t.hash)
+
+			err := ctx.client.SetCommitBuildStatus(t.hash, t.status)
+			if err != nil {
+				errs[n] = fmt.Errorf("key %s on %s: %w", t.status.Key, t.hash, err)
+			}
+		}(i, t)
+	}
+
+	wg.Wait()
+
+	return aggregateErrors(errs)
+}
+
+// statusTargetHashes resolves commits, which build status is set on
+func (cmd *OutCommand) statusTargetHashes(ctx *outContext) ([]string, error) {
+	switch ctx.params.StatusTarget {
+	case models.VersionStatusTarget:
+		return []string{ctx.version.Ref}, nil
+
+	case models.MergeParentsStatusTarget:
+		parents, err := cmd.gitGetHeadParents(ctx.repoPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(parents) < 2 {
+			// HEAD is not a merge commit, so it is the commit BitBucket knows
+			return cmd.headHashes(ctx)
+		}
+
+		return cmd.pullRequestParents(ctx, parents)
+
+	case models.AllPullRequestCommitsStatusTarget:
+		commits, err := ctx.client.GetPullRequestCommits(ctx.version.ID)
+		if err != nil {
+			return nil, fmt.Errorf("resource/out: get PR commits: %w", err)
+		}
+
+		hashes := make([]string, 0, len(commits))
+		for _, c := range commits {
+			hashes = append(hashes, c.Hash)
+		}
+
+		return hashes, nil
+
+	default:
+		return cmd.headHashes(ctx)
+	}
+}
+
+// pullRequestParents of local merge commit, leaving out the destination branch side.
+// The parent being the version is picked, otherwise parents not reachable from PR destination
+func (cmd *OutCommand) pullRequestParents(ctx *outContext, parents []string) ([]string, error) {
+	for _, p := range parents {
+		if sameCommit(p, ctx.version.Ref) {
+			return []string{p}, nil
+		}
+	}
+
+	pr, err := ctx.pullRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := cmd.gitNotReachableFrom(ctx.repoPath, parents, pr.Dest.Commit.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("resource/out: no parent of HEAD is on PR source side, all reachable from %s", pr.Dest.Commit.Hash)
+	}
+
+	return hashes, nil
+}
+
+func (cmd *OutCommand) headHashes(ctx *outContext) ([]string, error) {
+	hash, err := cmd.commitHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{hash}, nil
+}
+
+// buildStatuses from params. Entries of statuses list fall back to top level params for omitted fields
+func (cmd *OutCommand) buildStatuses(ctx *outContext) ([]bitbucket.CommitBuildStatusRequest, error) {
+	if len(ctx.params.Statuses) == 0 {
+		status, err := cmd.paramsStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return []bitbucket.CommitBuildStatusRequest{*status}, nil
+	}
+
+	build := newBuildEnv()
+	statuses := make([]bitbucket.CommitBuildStatusRequest, 0, len(ctx.params.Statuses))
+
+	for _, s := range ctx.params.Statuses {
+		status := bitbucket.CommitBuildStatusRequest{
+			Key:         s.Key,
+			Name:        firstNonEmpty(s.Name, ctx.params.Name),
+			Description: firstNonEmpty(s.Description, ctx.params.Description),
+			URL:         firstNonEmpty(s.URL, ctx.params.URL, build.URL()),
+			State:       bitbucket.CommitBuildStatus(s.Status),
+		}
+
+		err := ctx.render(&status.Key, &status.Name, &status.Description, &status.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(status.URL) == 0 {
+			return nil, fmt.Errorf("resource/out: url of status %s is empty and cannot be derived from build metadata", status.Key)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// paramsStatus from top level params, with status, description and url optionally read from files
+func (cmd *OutCommand) paramsStatus(ctx *outContext) (*bitbucket.CommitBuildStatusRequest, error) {
+	key, name, description, url := ctx.params.Key, ctx.params.Name, ctx.params.Description, ctx.params.URL
+
+	build := newBuildEnv()
+
+	if len(key) == 0 {
+		key = build.StatusKey()
+	}
+
+	if len(url) == 0 {
+		url = build.URL()
+	}
+
+	err := ctx.render(&key, &name, &description, &url)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := cmd.fileOrParam(ctx, ctx.params.StatusFile, ctx.params.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	status = strings.ToUpper(status)
+
+	err = models.ValidateCommitBuildStatus(status)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: %w", err)
+	}
+
+	description, err = cmd.fileOrParam(ctx, ctx.params.DescriptionFile, description)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err = cmd.fileOrParam(ctx, ctx.params.URLFile, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(url) == 0 {
+		return nil, errors.New("resource/out: url is empty and cannot be derived from build metadata")
+	}
+
+	return &bitbucket.CommitBuildStatusRequest{
+		Key:         key,
+		Name:        name,
+		Description: description,
+		URL:         url,
+		State:       bitbucket.CommitBuildStatus(status),
+	}, nil
+}
+
+// fileOrParam reads trimmed content of the file written by a task. Param is used when path is not set,
+// or as a fallback when the file is missing, ie. task failed before writing it
+func (cmd *OutCommand) fileOrParam(ctx *outContext, path string, param string) (string, error) {
+	if len(path) == 0 {
+		return param, nil
+	}
+
+	content, err := ctx.readFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && len(param) > 0 {
+			cmd.Logger.Debugf("resource/out: file %s missing, using param", path)
+			return param, nil
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(content), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// aggregateErrors into single one listing all of them, nil if there are none
+func aggregateErrors(errs []error) error {
+	messages := make([]string, 0, len(errs))
+
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d of %d failed: %s", len(messages), len(errs), strings.Join(messages, "; "))
+}
+
+// stopSuperseded marks in progress build statuses of previous PR heads and commits other than current head as stopped,
+// so builds aborted by newer pushes are not left in progress forever. Only statuses of the keys in params are stopped
+func (cmd *OutCommand) stopSuperseded(ctx *outContext) (models.Metadata, error) {
+	pr, err := ctx.pullRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	head := pr.Source.Commit.Hash
+
+	hashes, err := cmd.supersededHashes(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := cmd.statusKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, 0)
+	targets := make([]commitStatus, 0)
+
+	for _, hash := range hashes {
+		statuses, err := ctx.client.GetCommitBuildStatuses(hash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get statuses of %s: %w", hash, err))
+			continue
+		}
+
+		for i := range statuses {
+			s := &statuses[i]
+
+			if s.State != bitbucket.InProgressCommitBuildStatus || !keys[s.Key] {
+				continue
+			}
+
+			cmd.Logger.Debugf("resource/out: stop status %s of %s superseded by %s", s.Key, hash, head)
+
+			s.State = bitbucket.StoppedCommitBuildStatus
+			s.Description = "superseded by " + head
+
+			targets = append(targets, commitStatus{hash: hash, status: s})
+		}
+	}
+
+	err = cmd.submitStatuses(ctx, targets)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = aggregateErrors(errs)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: stop superseded statuses: %w", err)
+	}
+
+	return models.Metadata{
+		{Name: models.CommitMetadataName, Value: head},
+		{Name: models.StoppedMetadataName, Value: strconv.Itoa(len(targets))},
+	}, nil
+}
+
+// supersededHashes are source heads recorded in PR activity on each push, including ones rewritten by force push,
+// together with current PR commits. Head is excluded
+func (cmd *OutCommand) supersededHashes(ctx *outContext, head string) ([]string, error) {
+	updates, err := ctx.client.GetPullRequestUpdates(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get PR activity: %w", err)
+	}
+
+	commits, err := ctx.client.GetPullRequestCommits(ctx.version.ID)
+	if err != nil {
+		return nil, fmt.Errorf("resource/out: get PR commits: %w", err)
+	}
+
+	candidates := make([]string, 0, len(updates)+len(commits))
+	for _, u := range updates {
+		candidates = append(candidates, u.Source.Commit.Hash)
+	}
+	for _, c := range commits {
+		candidates = append(candidates, c.Hash)
+	}
+
+	return uniqueCommits(candidates, head), nil
+}
+
+// uniqueCommits drops empty hashes, duplicates and the excluded one. Abbreviated hashes match the full ones
+func uniqueCommits(hashes []string, exclude string) []string {
+	unique := make([]string, 0, len(hashes))
+
+	for _, h := range hashes {
+		if len(h) == 0 || sameCommit(h, exclude) {
+			continue
+		}
+
+		duplicate := false
+
+		for i, u := range unique {
+			if sameCommit(h, u) {
+				duplicate = true
+
+				if len(h) > len(u) {
+					unique[i] = h
+				}
+			}
+		}
+
+		if !duplicate {
+			unique = append(unique, h)
+		}
+	}
+
+	return unique
+}
+
+// statusKeys set by this resource according to params, either key or keys of statuses list
+func (cmd *OutCommand) statusKeys(ctx *outContext) (map[string]bool, error) {
+	keys := make(map[string]bool)
+
+	for _, s := range ctx.params.Statuses {
+		key := s.Key
+
+		err := ctx.render(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[key] = true
+	}
+
+	if len(keys) > 0 {
+		return keys, nil
+	}
+
+	key := ctx.params.Key
+	if len(key) == 0 {
+		key = newBuildEnv().StatusKey()
+	}
+
+	err := ctx.render(&key)
+	if err != nil {
+		return nil, err
+	}
+
+	keys[key] = true
+
+	return keys, nil
+}
@@ -0,0 +1,88 @@
+package resource
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/concourse"
+)
+
+func TestUniqueCommits(t *testing.T) {
+	cases := []struct {
+		name    string
+		hashes  []string
+		exclude string
+		want    []string
+	}{
+		{"empty", nil, "abc", []string{}},
+		{"head excluded", []string{"abc123", "def456"}, "abc123", []string{"def456"}},
+		{"abbreviated head excluded", []string{"abc123ff", "def456"}, "abc123", []string{"def456"}},
+		{"abbreviated duplicate keeps full", []string{"def456", "def456aa", "def456"}, "", []string{"def456aa"}},
+		{"empty hash dropped", []string{"", "def456"}, "abc", []string{"def456"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := uniqueCommits(c.hashes, c.exclude)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// countingTransport answers every request with empty JSON, tracking the peak of requests in flight
+type countingTransport struct {
+	inFlight, peak, total int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := atomic.AddInt32(&t.inFlight, 1)
+	defer atomic.AddInt32(&t.inFlight, -1)
+
+	atomic.AddInt32(&t.total, 1)
+
+	for {
+		peak := atomic.LoadInt32(&t.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&t.peak, peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("{}")), Request: req}, nil
+}
+
+func TestSubmitStatusesConcurrencyLimit(t *testing.T) {
+	transport := &countingTransport{}
+	client := bitbucket.NewClient("workspace", "slug", &bitbucket.Auth{AccessToken: "token"}, &http.Client{Transport: transport})
+
+	status := &bitbucket.CommitBuildStatusRequest{Key: "ios", State: bitbucket.InProgressCommitBuildStatus}
+
+	targets := make([]commitStatus, 0, 50)
+	for i := 0; i < 50; i++ {
+		targets = append(targets, commitStatus{hash: strings.Repeat("a", i+1), status: status})
+	}
+
+	cmd := OutCommand{Logger: &concourse.Logger{}}
+
+	err := cmd.submitStatuses(&outContext{client: client}, targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transport.total != 50 {
+		t.Errorf("expected 50 requests, got %d", transport.total)
+	}
+
+	if transport.peak > maxConcurrentStatuses {
+		t.Errorf("expected at most %d requests in flight, got %d", maxConcurrentStatuses, transport.peak)
+	}
+}
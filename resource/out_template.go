@@ -0,0 +1,189 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// envPattern matches $VAR and ${VAR} references, kept for compatibility with params written before templating
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// defaultStatusKey is used when build metadata is not available
+const defaultStatusKey = "BUILD"
+
+// buildEnv of Concourse, see https://concourse-ci.org/implementing-resource-types.html#resource-metadata
+type buildEnv struct {
+	ID                   string
+	Name                 string
+	JobName              string
+	PipelineName         string
+	PipelineInstanceVars string
+	TeamName             string
+	ExternalURL          string
+}
+
+// URL of the build in Concourse UI, empty if external URL is unknown
+func (b buildEnv) URL() string {
+	if len(b.ExternalURL) == 0 || len(b.TeamName) == 0 || len(b.PipelineName) == 0 || len(b.JobName) == 0 {
+		return ""
+	}
+
+	u := fmt.Sprintf("%s/teams/%s/pipelines/%s/jobs/%s/builds/%s", strings.TrimSuffix(b.ExternalURL, "/"),
+		url.PathEscape(b.TeamName), url.PathEscape(b.PipelineName), url.PathEscape(b.JobName), url.PathEscape(b.Name))
+
+	if query := instanceVarsQuery(b.PipelineInstanceVars); len(query) > 0 {
+		u += "?" + query
+	}
+
+	return u
+}
+
+// StatusKey unique per pipeline and job, so statuses of parallel jobs don't overwrite each other
+func (b buildEnv) StatusKey() string {
+	if len(b.PipelineName) == 0 || len(b.JobName) == 0 {
+		return defaultStatusKey
+	}
+
+	return b.PipelineName + "-" + b.JobName
+}
+
+// instanceVarsQuery encodes instance vars of pipeline the way Concourse UI does, ie. vars.branch=%22main%22
+func instanceVarsQuery(instanceVars string) string {
+	if len(instanceVars) == 0 {
+		return ""
+	}
+
+	var vars map[string]interface{}
+
+	err := json.Unmarshal([]byte(instanceVars), &vars)
+	if err != nil {
+		return ""
+	}
+
+	query := url.Values{}
+	addInstanceVars(query, "vars", vars)
+
+	return query.Encode()
+}
+
+func addInstanceVars(query url.Values, prefix string, vars map[string]interface{}) {
+	for k, v := range vars {
+		if nested, ok := v.(map[string]interface{}); ok {
+			addInstanceVars(query, prefix+"."+k, nested)
+			continue
+		}
+
+		value, _ := json.Marshal(v)
+		query.Add(prefix+"."+k, string(value))
+	}
+}
+
+// templateData is accessible by out params, ie. {{ .Build.JobName }} or {{ .PullRequest.Title }}
+type templateData struct {
+	Build   buildEnv
+	Version models.Version
+
+	ctx *outContext
+}
+
+// PullRequest of the version, fetched from API on first use
+func (d templateData) PullRequest() (*bitbucket.PullRequestEntity, error) {
+	return d.ctx.pullRequest()
+}
+
+func newBuildEnv() buildEnv {
+	return buildEnv{
+		ID:                   os.Getenv("BUILD_ID"),
+		Name:                 os.Getenv("BUILD_NAME"),
+		JobName:              os.Getenv("BUILD_JOB_NAME"),
+		PipelineName:         os.Getenv("BUILD_PIPELINE_NAME"),
+		PipelineInstanceVars: os.Getenv("BUILD_PIPELINE_INSTANCE_VARS"),
+		TeamName:             os.Getenv("BUILD_TEAM_NAME"),
+		ExternalURL:          os.Getenv("ATC_EXTERNAL_URL"),
+	}
+}
+
+// render texts in place as templates
+func (ctx *outContext) render(texts ...*string) error {
+	data := templateData{
+		Build:   newBuildEnv(),
+		Version: ctx.version,
+		ctx:     ctx,
+	}
+
+	funcs := template.FuncMap{
+		"env": os.Getenv,
+		"file": func(path string) (string, error) {
+			content, err := ctx.readFile(path)
+			return strings.TrimSpace(content), err
+		},
+	}
+
+	for _, text := range texts {
+		tmpl, err := template.New("param").Funcs(funcs).Parse(envToTemplate(*text))
+		if err != nil {
+			return fmt.Errorf("resource/out: parse template %q: %w", *text, err)
+		}
+
+		var buf bytes.Buffer
+
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			return fmt.Errorf("resource/out: render template %q: %w", *text, err)
+		}
+
+		*text = buf.String()
+	}
+
+	return nil
+}
+
+// envToTemplate rewrites references of set environment variables in literal text into template actions.
+// References of unset variables and text within actions, ie. {{ range $i, $v := . }}, are left intact
+func envToTemplate(s string) string {
+	var b strings.Builder
+
+	for len(s) > 0 {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			b.WriteString(envRefsToActions(s))
+			break
+		}
+
+		b.WriteString(envRefsToActions(s[:start]))
+		s = s[start:]
+
+		end := strings.Index(s, "}}")
+		if end < 0 {
+			// unterminated action is reported by template parser
+			b.WriteString(s)
+			break
+		}
+
+		b.WriteString(s[:end+2])
+		s = s[end+2:]
+	}
+
+	return b.String()
+}
+
+func envRefsToActions(text string) string {
+	return envPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		name := strings.Trim(ref, "${}")
+
+		if _, ok := os.LookupEnv(name); !ok {
+			return ref
+		}
+
+		return fmt.Sprintf("{{ env %q }}", name)
+	})
+}
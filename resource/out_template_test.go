@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+func TestRender(t *testing.T) {
+	setenv(t, map[string]string{"BUILD_ID": "12", "BUILD_JOB_NAME": "test=unit", "i": "x"})
+
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "coverage"), []byte("85%\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &outContext{
+		version: models.Version{Ref: "abc", ID: "7"},
+		workDir: dir,
+	}
+
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"$BUILD_JOB_NAME #$BUILD_ID", "test=unit #12"},
+		{"${BUILD_ID}x", "12x"},
+		{"$BUILD_IDX $BUILD_ID", "$BUILD_IDX 12"},
+		{"costs $5", "costs $5"},
+		{"PR {{ .Version.ID }} at {{ .Version.Ref }}", "PR 7 at abc"},
+		{`{{ with $i := .Version.ID }}{{ $i }}{{ end }} $i`, "7 x"},
+		{`coverage {{ file "coverage" }}`, "coverage 85%"},
+	}
+
+	for _, c := range cases {
+		text := c.text
+
+		err := ctx.render(&text)
+		if err != nil {
+			t.Fatalf("%s: %s", c.text, err)
+		}
+
+		if text != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.text, c.expected, text)
+		}
+	}
+}
+
+// setenv for the duration of the test
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		k := k
+		prev, ok := os.LookupEnv(k)
+
+		os.Setenv(k, v)
+
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func TestBuildEnv(t *testing.T) {
+	build := buildEnv{
+		Name:         "7",
+		JobName:      "unit-test",
+		PipelineName: "app",
+		TeamName:     "main",
+		ExternalURL:  "https://ci.example.com/",
+	}
+
+	cases := []struct {
+		name string
+		edit func(b *buildEnv)
+		url  string
+		key  string
+	}{
+		{"plain", func(b *buildEnv) {}, "https://ci.example.com/teams/main/pipelines/app/jobs/unit-test/builds/7", "app-unit-test"},
+		{"escaped", func(b *buildEnv) { b.TeamName, b.JobName, b.Name = "qa team", "test/ios", "7.1" },
+			"https://ci.example.com/teams/qa%20team/pipelines/app/jobs/test%2Fios/builds/7.1", "app-test/ios"},
+		{"instance vars", func(b *buildEnv) { b.PipelineInstanceVars = `{"branch": "feature/x", "env": {"region": "eu", "n": 2}}` },
+			"https://ci.example.com/teams/main/pipelines/app/jobs/unit-test/builds/7?vars.branch=%22feature%2Fx%22&vars.env.n=2&vars.env.region=%22eu%22", "app-unit-test"},
+		{"invalid instance vars", func(b *buildEnv) { b.PipelineInstanceVars = `branch=main` },
+			"https://ci.example.com/teams/main/pipelines/app/jobs/unit-test/builds/7", "app-unit-test"},
+		{"no external url", func(b *buildEnv) { b.ExternalURL = "" }, "", "app-unit-test"},
+		{"no pipeline", func(b *buildEnv) { b.PipelineName = "" }, "", defaultStatusKey},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := build
+			c.edit(&b)
+
+			if url := b.URL(); url != c.url {
+				t.Errorf("expected url %s, got %s", c.url, url)
+			}
+
+			if key := b.StatusKey(); key != c.key {
+				t.Errorf("expected key %s, got %s", c.key, key)
+			}
+		})
+	}
+}
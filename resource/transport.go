@@ -0,0 +1,212 @@
+package resource
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	git "github.com/libgit2/git2go/v31"
+	"github.com/n7mobile/concourse-bitbucket-pr/bitbucket"
+	"github.com/n7mobile/concourse-bitbucket-pr/resource/models"
+)
+
+// transport gathers connection settings shared by BitBucket REST client and libgit2 remotes,
+// so both of them verify certificates the same way
+type transport struct {
+	source models.Source
+	tls    *tls.Config
+	proxy  *url.URL
+	auth   *bitbucket.Auth
+}
+
+func newTransport(src models.Source) (*transport, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if len(src.CACerts) > 0 && !pool.AppendCertsFromPEM([]byte(src.CACerts)) {
+		return nil, errors.New("resource/transport: ca_certs does not contain any valid PEM certificate")
+	}
+
+	var proxy *url.URL
+
+	if len(src.Proxy) > 0 {
+		proxy, err = url.Parse(src.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("resource/transport: proxy url: %w", err)
+		}
+	}
+
+	return &transport{
+		source: src,
+		tls: &tls.Config{
+			RootCAs:            pool,
+			InsecureSkipVerify: src.SkipSSLVerification,
+		},
+		proxy: proxy,
+		auth: &bitbucket.Auth{
+			Username:    src.Username,
+			Password:    src.Password,
+			AccessToken: src.AccessToken,
+			OAuthKey:    src.OAuthKey,
+			OAuthSecret: src.OAuthSecret,
+		},
+	}, nil
+}
+
+// HTTPClient for BitBucket REST API calls
+func (t transport) HTTPClient() *http.Client {
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.TLSClientConfig = t.tls
+	httpTransport.Proxy = t.httpProxy
+
+	return &http.Client{
+		Transport: httpTransport,
+	}
+}
+
+// BitbucketClient configured with source credentials and transport settings
+func (t transport) BitbucketClient() *bitbucket.Client {
+	return bitbucket.NewClient(t.source.Workspace, t.source.Slug, t.auth, t.HTTPClient())
+}
+
+// FetchOptions for libgit2 clone, fetch and submodule update of repository at remoteURL
+// Token based auth is passed as `x-token-auth` user, OAuth token is shared with REST client
+func (t transport) FetchOptions(remoteURL string) *git.FetchOptions {
+	httpClient := t.HTTPClient()
+
+	return t.fetchOptions(remoteURL, func() (string, string, error) {
+		return t.auth.GitCredentials(httpClient)
+	})
+}
+
+// SubmoduleFetchOptions for update of submodule at remoteURL.
+// Credentials configured for the host of submodule take precedence over the ones of resource repo
+func (t transport) SubmoduleFetchOptions(remoteURL string) *git.FetchOptions {
+	if u, err := url.Parse(remoteURL); err == nil {
+		for _, creds := range t.source.SubmoduleCredentials {
+			if strings.EqualFold(creds.Host, u.Hostname()) {
+				user, pass := creds.Username, creds.Password
+
+				return t.fetchOptions(remoteURL, func() (string, string, error) {
+					return user, pass, nil
+				})
+			}
+		}
+	}
+
+	return t.FetchOptions(remoteURL)
+}
+
+func (t transport) fetchOptions(remoteURL string, credentials func() (string, string, error)) *git.FetchOptions {
+	return &git.FetchOptions{
+		RemoteCallbacks: git.RemoteCallbacks{
+			CredentialsCallback: func(url, username_from_url string, allowed_types git.CredentialType) (*git.Credential, error) {
+				user, pass, err := credentials()
+				if err != nil {
+					return nil, fmt.Errorf("resource/transport: git credentials: %w", err)
+				}
+
+				return git.NewCredentialUserpassPlaintext(user, pass)
+			},
+			CertificateCheckCallback: t.certificateCheck,
+		},
+		ProxyOptions: t.gitProxy(remoteURL),
+	}
+}
+
+// certificateCheck accepts certificates trusted by libgit2 or chaining up to system roots extended by ca_certs
+func (t transport) certificateCheck(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
+	if t.tls.InsecureSkipVerify || valid {
+		return git.ErrorCodeOK
+	}
+
+	if cert.Kind != git.CertificateX509 || cert.X509 == nil {
+		return git.ErrorCodeCertificate
+	}
+
+	// libgit2 hands over only the leaf, without intermediates sent by the server. These have to be included
+	// in ca_certs, where they are trusted as roots
+	_, err := cert.X509.Verify(x509.VerifyOptions{
+		DNSName: hostname,
+		Roots:   t.tls.RootCAs,
+	})
+	if err != nil {
+		return git.ErrorCodeCertificate
+	}
+
+	return git.ErrorCodeOK
+}
+
+// httpProxy selects proxy for REST API request. Environment is used when proxy is not configured in source
+func (t transport) httpProxy(req *http.Request) (*url.URL, error) {
+	if t.proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+
+	if t.bypassProxy(req.URL.Hostname()) {
+		return nil, nil
+	}
+
+	return t.proxy, nil
+}
+
+// gitProxy selects proxy for libgit2 remote. Git configuration and environment are used when proxy is not configured in source
+func (t transport) gitProxy(remoteURL string) git.ProxyOptions {
+	if t.proxy == nil {
+		return git.ProxyOptions{Type: git.ProxyTypeAuto}
+	}
+
+	if u, err := url.Parse(remoteURL); err == nil && t.bypassProxy(u.Hostname()) {
+		return git.ProxyOptions{Type: git.ProxyTypeNone}
+	}
+
+	return git.ProxyOptions{
+		Type: git.ProxyTypeSpecified,
+		Url:  t.proxy.String(),
+	}
+}
+
+// bypassProxy matches host against comma separated no_proxy list of domains, IPs and CIDRs
+func (t transport) bypassProxy(host string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(t.source.NoProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		if len(entry) == 0 {
+			continue
+		}
+
+		if entry == "*" {
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+
+			continue
+		}
+
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+
+		entry = strings.TrimPrefix(entry, ".")
+
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+
+	return false
+}